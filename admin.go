@@ -0,0 +1,168 @@
+package governor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// redacted replaces secret values in config dumps.
+const redacted = "********"
+
+// secretMarkers mark a key as holding a secret when it contains any of them.
+var secretMarkers = []string{"password", "secret", "token"}
+
+// GetAppSection returns the [app] section as a map, with environment
+// overrides applied to the keys it defines and ${scheme:reference} tokens
+// interpolated.  A WithEnvironment view merges the [app.env] section over it.
+func (ms *ManagerService) GetAppSection(app string) (map[string]interface{}, error) {
+	m, err := ms.rawAppSection(app)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := ms.interpolateMap(m); err != nil {
+		return nil, fmt.Errorf("Configuration '%s' under [%s] heading: %s", key, app, err)
+	}
+	return m, nil
+}
+
+// rawAppSection builds GetAppSection's map without interpolation.
+func (ms *ManagerService) rawAppSection(app string) (map[string]interface{}, error) {
+	m, ok := ms.getTable(app)
+	if !ok {
+		if _, exists := ms.Get(app); !exists {
+			return nil, fmt.Errorf("Configuration missing [%s] heading.", app)
+		}
+		return nil, fmt.Errorf("Configuration '%s' is not a section.", app)
+	}
+
+	if envSection, ok := ms.envSection(app); ok {
+		delete(m, ms.env)
+		for k, v := range envSection {
+			m[k] = v
+		}
+	}
+	for k := range m {
+		if env, ok := os.LookupEnv(envKey(app, k)); ok {
+			m[k] = env
+		}
+	}
+	return m, nil
+}
+
+// isSecretKey reports whether values under key must be redacted.
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, marker := range secretMarkers {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactSection returns a copy of section with secret values masked,
+// including credentials embedded in URLs and secrets in nested tables and
+// arrays.
+func redactSection(section map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(section))
+	for k, v := range section {
+		switch {
+		case isSecretKey(k):
+			out[k] = redacted
+		case strings.ToLower(k) == "dburl":
+			out[k] = redactURL(fmt.Sprint(v))
+		default:
+			out[k] = redactValue(v)
+		}
+	}
+	return out
+}
+
+// redactValue masks secrets inside v, descending into tables and arrays of
+// tables such as [[app.replicas]].
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return redactSection(v)
+	case []map[string]interface{}:
+		out := make([]map[string]interface{}, len(v))
+		for i, e := range v {
+			out[i] = redactSection(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = redactValue(e)
+		}
+		return out
+	case string:
+		return redactUserinfo(v)
+	default:
+		return v
+	}
+}
+
+// redactUserinfo masks the credentials of a value which parses as a URL:
+// the password when there is one, otherwise the username, which may itself
+// be a token.  Other values are returned as they are.
+func redactUserinfo(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.User == nil {
+		return s
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	} else {
+		u.User = url.User(redacted)
+	}
+	return u.String()
+}
+
+// redactURL masks the password in a connection URL.  Unparseable URLs are
+// masked entirely since they may still contain credentials.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redacted
+	}
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+	}
+	return u.String()
+}
+
+// configHandler writes the app's configuration, redacted, as JSON.  Values
+// are dumped before interpolation, so secrets resolved from placeholders are
+// never served.
+func (ms *ManagerService) configHandler(app string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		section, err := ms.rawAppSection(app)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(redactSection(section))
+	}
+}
+
+// configureAdminConfig mounts GET /admin/config behind basic auth when
+// admin_config is enabled for app.
+func (ms *ManagerService) configureAdminConfig(api *API, app string) {
+	if enabled, _ := ms.GetAppPropertyBool(app, "admin_config"); !enabled {
+		return
+	}
+	auth := ms.adminAuth(app)
+	if auth == nil {
+		ms.Log().Warnf("CreateAPI: admin_config under [%s] requires basic_auth_user and basic_auth_password.", app)
+		return
+	}
+	api.GET("/admin/config", auth(ms.configHandler(app)).ServeHTTP)
+}
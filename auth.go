@@ -0,0 +1,64 @@
+package governor
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+)
+
+// BasicAuth returns middleware which rejects requests that do not carry the
+// given HTTP basic auth credentials.
+func BasicAuth(realm string, user string, password string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u, p, ok := r.BasicAuth()
+			if !ok ||
+				subtle.ConstantTimeCompare([]byte(u), []byte(user)) != 1 ||
+				subtle.ConstantTimeCompare([]byte(p), []byte(password)) != 1 {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q", realm))
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireAuth is route middleware which applies the API's authentication,
+// set with SetAuth, to a single route:
+//
+//	api.GET("/admin", h, governor.RequireAuth)
+//
+// Requests are refused with 401 Unauthorized when the API has no
+// authentication configured.
+func RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api, _ := r.Context().Value(apiKey{}).(*API)
+		if api == nil || api.auth == nil {
+			if api != nil {
+				api.ManagerService.AppLog(api.app).Warnf("RequireAuth: no authentication configured for [%s], refusing %s.", api.app, r.URL.Path)
+			}
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		api.auth(next).ServeHTTP(w, r)
+	})
+}
+
+// SetAuth sets the middleware RequireAuth applies, replacing the basic auth
+// CreateAPI configures from basic_auth_user and basic_auth_password.
+func (api *API) SetAuth(mw Middleware) {
+	api.auth = mw
+}
+
+// adminAuth returns the basic auth middleware configured for app through
+// basic_auth_user and basic_auth_password, or nil when either is unset.
+// Admin endpoints are only mounted when this is available.
+func (ms *ManagerService) adminAuth(app string) Middleware {
+	user, _ := ms.GetAppProperty(app, "basic_auth_user")
+	password, _ := ms.GetAppProperty(app, "basic_auth_password")
+	if user == "" || password == "" {
+		return nil
+	}
+	return BasicAuth(app, user, password)
+}
@@ -0,0 +1,45 @@
+//go:build autocert
+// +build autocert
+
+package governor
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// configureAutocert provisions certificates from Let's Encrypt when autocert
+// is enabled for app.  autocert_domains lists the hosts certificates may be
+// issued for and autocert_cache_dir stores them between restarts.  ACME
+// HTTP-01 challenges are answered on port 80, or http_redirect_port, which
+// otherwise redirects to HTTPS.
+func (ms *ManagerService) configureAutocert(api *API, app string) error {
+	enabled, err := ms.GetAppPropertyBool(app, "autocert")
+	if err != nil || !enabled {
+		return nil
+	}
+	if api.tlsCert != "" {
+		return fmt.Errorf("autocert and tls_cert cannot both be set under [%s] heading.", app)
+	}
+
+	domains, err := ms.GetAppPropertyStrings(app, "autocert_domains")
+	if err != nil {
+		return err
+	}
+	cache, err := ms.GetAppProperty(app, "autocert_cache_dir")
+	if err != nil {
+		return err
+	}
+
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Cache:      autocert.DirCache(cache),
+	}
+
+	api.TLSConfig = m.TLSConfig()
+	api.challenge = m.HTTPHandler
+
+	return ms.configureRedirect(api, app, "80")
+}
@@ -0,0 +1,17 @@
+//go:build !autocert
+// +build !autocert
+
+package governor
+
+import (
+	"fmt"
+)
+
+// configureAutocert rejects autocert configuration in builds without the
+// autocert tag, so the ACME client is only compiled in when requested.
+func (ms *ManagerService) configureAutocert(api *API, app string) error {
+	if enabled, err := ms.GetAppPropertyBool(app, "autocert"); err == nil && enabled {
+		return fmt.Errorf("autocert is enabled under [%s] heading but governor was built without the autocert tag.", app)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package governor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+
+	"github.com/lakesite/ls-superbase"
+)
+
+// RegisterDatastore registers initFn as the backend for apps whose dbdriver
+// is driver, so datastores other than the SQL databases superbase supports
+// (Redis, for instance) share the manager's lifecycle.  InitDatastore calls
+// initFn with the app's config section, the health checks ping the returned
+// store when it has a Ping(ctx) or Ping() method, and Close closes it.
+func (ms *ManagerService) RegisterDatastore(driver string, initFn func(cfg map[string]interface{}) (io.Closer, error)) {
+	ms.dbmu.Lock()
+	defer ms.dbmu.Unlock()
+
+	if ms.backends == nil {
+		ms.backends = make(map[string]func(map[string]interface{}) (io.Closer, error))
+	}
+	ms.backends[driver] = initFn
+}
+
+// Datastore returns the store created for app by a registered backend.
+func (ms *ManagerService) Datastore(app string) (io.Closer, bool) {
+	ms.dbmu.RLock()
+	defer ms.dbmu.RUnlock()
+
+	store, ok := ms.stores[app]
+	return store, ok
+}
+
+// backend returns the registered backend for app's dbdriver, if any.
+func (ms *ManagerService) backend(app string) (func(map[string]interface{}) (io.Closer, error), bool) {
+	driver, err := ms.GetAppProperty(app, "dbdriver")
+	if err != nil {
+		return nil, false
+	}
+
+	ms.dbmu.RLock()
+	defer ms.dbmu.RUnlock()
+
+	initFn, ok := ms.backends[driver]
+	return initFn, ok
+}
+
+// initBackend creates app's store with initFn unless a reachable store
+// already exists.  With force set the store is always recreated.
+func (ms *ManagerService) initBackend(app string, initFn func(map[string]interface{}) (io.Closer, error), force bool) error {
+	if existing, ok := ms.Datastore(app); ok && !force && pingStore(context.Background(), existing) == nil {
+		return nil
+	}
+
+	cfg, err := ms.GetAppSection(app)
+	if err != nil {
+		return err
+	}
+	store, err := initFn(cfg)
+	if err != nil {
+		err = fmt.Errorf("[%s] %s", app, err)
+		ms.emit(EventDatastoreConnected, app, err)
+		return err
+	}
+
+	ms.dbmu.Lock()
+	if ms.stores == nil {
+		ms.stores = make(map[string]io.Closer)
+	}
+	previous := ms.stores[app]
+	ms.stores[app] = store
+	ms.dbmu.Unlock()
+
+	if previous != nil {
+		if err := previous.Close(); err != nil {
+			ms.Log().Warnf("InitDatastore: closing previous [%s] store: %s", app, err)
+		}
+	}
+	ms.emit(EventDatastoreConnected, app, nil)
+	return nil
+}
+
+// pingStore checks a backend store when it supports pinging.
+func pingStore(ctx context.Context, store io.Closer) error {
+	switch s := store.(type) {
+	case interface{ Ping(context.Context) error }:
+		return s.Ping(ctx)
+	case interface{ Ping() error }:
+		return s.Ping()
+	}
+	return nil
+}
+
+// CloseDatastore closes app's datastore, gorm connection or backend store,
+// and forgets it, leaving other apps' datastores open.  A connection shared
+// with other apps stays open until the last of them is closed.  Closing an
+// app without an open datastore is a no-op returning nil.  An app holds a
+// single datastore, so it is selected by app alone.
+func (ms *ManagerService) CloseDatastore(app string) error {
+	ms.dbmu.Lock()
+	dbc := ms.DBConfig[app]
+	pool := ms.appPools[app]
+	store := ms.stores[app]
+	delete(ms.DBConfig, app)
+	delete(ms.appPools, app)
+	delete(ms.stores, app)
+	ms.dbmu.Unlock()
+
+	var err error
+	switch {
+	case pool != nil:
+		ms.releasePool(pool)
+	case dbc != nil && dbc.Connection != nil:
+		err = dbc.Connection.Close()
+	}
+	if store != nil {
+		if serr := store.Close(); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("CloseDatastore: [%s] %s", app, err)
+	}
+	return nil
+}
+
+// Close closes every datastore the manager holds, both gorm connections and
+// backend stores, returning the first error encountered.  A connection shared
+// by several apps is closed once.
+func (ms *ManagerService) Close() error {
+	ms.dbmu.Lock()
+	conns := ms.DBConfig
+	stores := ms.stores
+	ms.DBConfig = make(map[string]*superbase.DBConfig)
+	ms.stores = nil
+	ms.pools = nil
+	ms.appPools = nil
+	ms.dbmu.Unlock()
+
+	var first error
+	closed := make(map[*sql.DB]bool, len(conns))
+	for app, dbc := range conns {
+		// shared pools appear under every app using them, possibly through
+		// differently configured handles
+		if dbc == nil || dbc.Connection == nil || closed[dbc.Connection.DB()] {
+			continue
+		}
+		closed[dbc.Connection.DB()] = true
+		if err := dbc.Connection.Close(); err != nil && first == nil {
+			first = fmt.Errorf("Close: [%s] %s", app, err)
+		}
+	}
+	for app, store := range stores {
+		if err := store.Close(); err != nil && first == nil {
+			first = fmt.Errorf("Close: [%s] %s", app, err)
+		}
+	}
+	return first
+}
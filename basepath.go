@@ -0,0 +1,41 @@
+package governor
+
+import (
+	"net/http"
+	"strings"
+)
+
+// configureBasePath reads base_path for app, under which every route,
+// including the health endpoints, is mounted.  It defaults to "/".
+func (ms *ManagerService) configureBasePath(api *API, app string) {
+	base, _ := ms.GetAppProperty(app, "base_path")
+	base = "/" + strings.Trim(base, "/")
+	if base == "/" {
+		base = ""
+	}
+	api.basePath = base
+}
+
+// mountBasePath returns h serving only requests under the API's base path,
+// with the prefix removed so routes are matched as registered.
+func (api *API) mountBasePath(h http.Handler) http.Handler {
+	if api.basePath == "" {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		if p != api.basePath && !strings.HasPrefix(p, api.basePath+"/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = strings.TrimPrefix(p, api.basePath)
+		if r2.URL.Path == "" {
+			r2.URL.Path = "/"
+		}
+		r2.URL.RawPath = ""
+		h.ServeHTTP(w, r2)
+	})
+}
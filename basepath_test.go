@@ -0,0 +1,100 @@
+package governor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConfigureBasePath(t *testing.T) {
+	cases := []struct {
+		name   string
+		config string
+		env    string
+		want   string
+	}{
+		{name: "unset", want: ""},
+		{name: "root", config: `base_path = "/"`, want: ""},
+		{name: "prefix", config: `base_path = "/api/myapp"`, want: "/api/myapp"},
+		{name: "trailing slash", config: `base_path = "/api/myapp/"`, want: "/api/myapp"},
+		{name: "no leading slash", config: `base_path = "api"`, want: "/api"},
+		{name: "env", env: "/from/env", want: "/from/env"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.env != "" {
+				t.Setenv("TESTAPP_BASE_PATH", c.env)
+			}
+			ms := newTestManager(t, "[testapp]\n"+c.config+"\n")
+			api := newTestAPI()
+			ms.configureBasePath(api, "testapp")
+			if api.basePath != c.want {
+				t.Errorf("basePath = %q, want %q", api.basePath, c.want)
+			}
+		})
+	}
+}
+
+func TestBasePathRouting(t *testing.T) {
+	cases := []struct {
+		name  string
+		base  string
+		paths map[string]int
+	}{
+		{
+			name: "unset",
+			paths: map[string]int{
+				"/thing":   http.StatusTeapot,
+				"/":        http.StatusAccepted,
+				"/readyz":  http.StatusOK,
+				"/metrics": http.StatusOK,
+			},
+		},
+		{
+			name: "prefix",
+			base: "/api/myapp",
+			paths: map[string]int{
+				"/api/myapp/thing":   http.StatusTeapot,
+				"/api/myapp":         http.StatusAccepted,
+				"/api/myapp/":        http.StatusAccepted,
+				"/api/myapp/readyz":  http.StatusOK,
+				"/api/myapp/metrics": http.StatusOK,
+				"/thing":             http.StatusNotFound,
+				"/readyz":            http.StatusNotFound,
+				"/api/myappthing":    http.StatusNotFound,
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ms := newTestManager(t, "[testapp]\nport = 0\nmetrics = true\nbase_path = \""+c.base+"\"\n")
+			api, err := ms.createAPI("testapp")
+			if err != nil {
+				t.Fatalf("createAPI: %s", err)
+			}
+			api.GET("/thing", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			})
+			api.GET("/", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			})
+
+			h := api.newServer().Handler
+			for path, want := range c.paths {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+				if w.Code != want {
+					t.Errorf("GET %s = %d, want %d", path, w.Code, want)
+				}
+			}
+
+			found := false
+			for _, r := range api.Routes() {
+				found = found || r.Path == c.base+"/thing"
+			}
+			if !found {
+				t.Errorf("Routes() = %v, want %s/thing listed with the base path", api.Routes(), c.base)
+			}
+		})
+	}
+}
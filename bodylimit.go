@@ -0,0 +1,112 @@
+package governor
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// BodyLimitMiddleware is the name of the middleware installed by
+// max_body_bytes, for use with Exempt.
+const BodyLimitMiddleware = "body_limit"
+
+// configureBodyLimit installs the body limit middleware when max_body_bytes
+// is configured for app.
+func (ms *ManagerService) configureBodyLimit(api *API, app string) error {
+	if _, ok := ms.lookupAppProperty(app, "max_body_bytes"); !ok {
+		return nil
+	}
+
+	limit, err := ms.GetAppPropertySize(app, "max_body_bytes")
+	if err != nil {
+		return err
+	}
+	api.Use(BodyLimitMiddleware, api.BodyLimit(limit))
+
+	return nil
+}
+
+// BodyLimit returns middleware which rejects request bodies larger than limit
+// bytes with a 413 Payload Too Large JSON response.  Bodies with an
+// oversized Content-Length are rejected before the handler runs; otherwise
+// reads past the limit fail and the handler's response is replaced, or
+// written if the handler returns without one.
+func (api *API) BodyLimit(limit int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				api.WebService.JsonStatusResponse(w, "Request body too large.", http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			lw := &limitWriter{ResponseWriter: w, api: api}
+			r.Body = &limitBody{ReadCloser: http.MaxBytesReader(lw, r.Body, limit), exceeded: &lw.exceeded}
+			next.ServeHTTP(lw, r)
+
+			// a handler which hit the limit and wrote nothing still gets
+			// the 413 rather than an empty 200
+			if lw.exceeded && !lw.written {
+				lw.intercept()
+			}
+		})
+	}
+}
+
+// limitBody records when a read fails because the body limit was reached.
+type limitBody struct {
+	io.ReadCloser
+	exceeded *bool
+}
+
+func (b *limitBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		*b.exceeded = true
+	}
+	return n, err
+}
+
+// limitWriter replaces the handler's response with a 413 once the body limit
+// has been exceeded.
+type limitWriter struct {
+	http.ResponseWriter
+	api      *API
+	exceeded bool
+	replaced bool
+	written  bool
+}
+
+func (lw *limitWriter) intercept() bool {
+	if lw.replaced {
+		return true
+	}
+	if lw.exceeded && !lw.written {
+		lw.replaced = true
+		lw.api.WebService.JsonStatusResponse(lw.ResponseWriter, "Request body too large.", http.StatusRequestEntityTooLarge)
+		return true
+	}
+	return false
+}
+
+func (lw *limitWriter) WriteHeader(status int) {
+	if lw.intercept() {
+		return
+	}
+	lw.written = true
+	lw.ResponseWriter.WriteHeader(status)
+}
+
+func (lw *limitWriter) Write(b []byte) (int, error) {
+	if lw.intercept() {
+		return len(b), nil
+	}
+	lw.written = true
+	return lw.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController, so
+// streaming handlers can still flush.
+func (lw *limitWriter) Unwrap() http.ResponseWriter {
+	return lw.ResponseWriter
+}
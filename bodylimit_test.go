@@ -0,0 +1,70 @@
+package governor
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		chunked bool
+		handler http.HandlerFunc
+		want    int
+	}{
+		{
+			name: "within limit",
+			body: "small",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.ReadAll(r.Body)
+				w.WriteHeader(http.StatusTeapot)
+			},
+			want: http.StatusTeapot,
+		},
+		{
+			name: "content length over limit",
+			body: strings.Repeat("x", 64),
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				t.Error("handler ran for an oversized Content-Length")
+			},
+			want: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:    "read past limit then write",
+			body:    strings.Repeat("x", 64),
+			chunked: true,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.ReadAll(r.Body)
+				w.WriteHeader(http.StatusTeapot)
+			},
+			want: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:    "read past limit and write nothing",
+			body:    strings.Repeat("x", 64),
+			chunked: true,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.ReadAll(r.Body)
+			},
+			want: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			h := newTestAPI().BodyLimit(16)(c.handler)
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(c.body))
+			if c.chunked {
+				r.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+			if rec.Code != c.want {
+				t.Errorf("got %d, want %d", rec.Code, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package governor
+
+import (
+	"time"
+)
+
+// SetClock replaces the time source used by the manager's time-dependent
+// features, such as migration timestamps, so tests can control time.  Passing
+// nil restores time.Now.
+func (ms *ManagerService) SetClock(now func() time.Time) {
+	if now == nil {
+		now = time.Now
+	}
+	ms.clock.Store(now)
+}
+
+// now returns the current time from the manager's clock.
+func (ms *ManagerService) now() time.Time {
+	if now, ok := ms.clock.Load().(func() time.Time); ok {
+		return now()
+	}
+	return time.Now()
+}
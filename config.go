@@ -0,0 +1,165 @@
+package governor
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/pelletier/go-toml"
+)
+
+// Get queries the configuration tree by dotted path, returning the raw value
+// and true when it exists, or nil and false otherwise (including when no
+// configuration has been loaded).
+func (ms *ManagerService) Get(path string) (interface{}, bool) {
+	ms.mu.RLock()
+	var value interface{}
+	if ms.Config != nil {
+		value = ms.Config.Get(path)
+	}
+	ms.mu.RUnlock()
+
+	if value == nil && ms.parent != nil {
+		return ms.parent.Get(path)
+	}
+	return value, value != nil
+}
+
+// SetAppProperty sets property for app in the in-memory configuration, so
+// later lookups return value.  Nothing is written back to disk and a reload
+// replaces the value; an APPNAME_PROPERTY environment variable still takes
+// precedence.  Go integer types are stored as int64, and float32 as
+// float64, matching values parsed from TOML; unsigned values above
+// math.MaxInt64 are kept as they are.
+func (ms *ManagerService) SetAppProperty(app string, property string, value interface{}) {
+	switch v := value.(type) {
+	case int:
+		value = int64(v)
+	case int8:
+		value = int64(v)
+	case int16:
+		value = int64(v)
+	case int32:
+		value = int64(v)
+	case uint:
+		value = uintToInt64(uint64(v), value)
+	case uint8:
+		value = int64(v)
+	case uint16:
+		value = int64(v)
+	case uint32:
+		value = int64(v)
+	case uint64:
+		value = uintToInt64(v, value)
+	case float32:
+		value = float64(v)
+	}
+
+	ms.mu.Lock()
+	if ms.Config == nil {
+		ms.Config, _ = toml.TreeFromMap(map[string]interface{}{})
+	}
+	ms.Config.Set(app+"."+property, value)
+	ms.mu.Unlock()
+
+	ms.resetFeatures()
+}
+
+// uintToInt64 returns v as an int64, or value when v does not fit.
+func uintToInt64(v uint64, value interface{}) interface{} {
+	if v > math.MaxInt64 {
+		return value
+	}
+	return int64(v)
+}
+
+// ConfigSources returns the configuration sources the manager loaded, in the
+// order they were applied, so diagnostics can report which files took effect.
+// Environment providers appear as their prefix, e.g. "env:EXAMPLE_APP_".
+func (ms *ManagerService) ConfigSources() []string {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	return append([]string(nil), ms.sources...)
+}
+
+// ReloadConfigDiff reloads the configuration file given to InitManager, or
+// the provider given to InitManagerProvider, and returns the sorted list of
+// dotted keys which were added, removed or changed compared to the
+// previously loaded tree.
+func (ms *ManagerService) ReloadConfigDiff() (changed []string, err error) {
+	ms.mu.RLock()
+	provider := ms.provider
+	ms.mu.RUnlock()
+	if provider == nil {
+		return nil, errors.New("ReloadConfigDiff: InitManager has not loaded a configuration.")
+	}
+
+	tree, sources, err := loadProvider(provider)
+	if err != nil {
+		ms.emit(EventConfigLoaded, "", err)
+		return nil, err
+	}
+
+	ms.mu.Lock()
+	previous := ms.Config
+	ms.Config = tree
+	ms.sources = sources
+	ms.mu.Unlock()
+	ms.resetFeatures()
+
+	if err := ms.applyLogLevel(); err != nil {
+		ms.Log().Errorf("ReloadConfigDiff: %s", err)
+	}
+	if err := ms.checkLoadedPlaceholders(); err != nil {
+		ms.Log().Errorf("ReloadConfigDiff: %s", err)
+	}
+	ms.emit(EventConfigLoaded, "", nil)
+
+	return diffTrees(previous, tree), nil
+}
+
+// diffTrees returns the sorted dotted keys whose values differ between a and b.
+func diffTrees(a, b *toml.Tree) []string {
+	before := flattenTree(a)
+	after := flattenTree(b)
+
+	changed := []string{}
+	for k, v := range after {
+		if old, ok := before[k]; !ok || !reflect.DeepEqual(old, v) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+
+	return changed
+}
+
+// flattenTree maps every leaf value in tree to its dotted key.
+func flattenTree(tree *toml.Tree) map[string]interface{} {
+	flat := make(map[string]interface{})
+	if tree != nil {
+		flattenMap("", tree.ToMap(), flat)
+	}
+	return flat
+}
+
+func flattenMap(prefix string, m map[string]interface{}, flat map[string]interface{}) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if sub, ok := v.(map[string]interface{}); ok {
+			flattenMap(key, sub, flat)
+			continue
+		}
+		flat[key] = v
+	}
+}
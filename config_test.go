@@ -0,0 +1,37 @@
+package governor
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestSetAppPropertyIntegers(t *testing.T) {
+	values := []interface{}{
+		int(42), int8(42), int16(42), int32(42), int64(42),
+		uint(42), uint8(42), uint16(42), uint32(42), uint64(42),
+	}
+	for _, v := range values {
+		t.Run(fmt.Sprintf("%T", v), func(t *testing.T) {
+			ms := newTestManager(t, "[testapp]\n")
+			ms.SetAppProperty("testapp", "value", v)
+
+			raw, _ := ms.Get("testapp.value")
+			if raw != int64(42) {
+				t.Errorf("stored %T(%v), want int64(42)", raw, raw)
+			}
+			if n, err := ms.GetAppPropertyInt("testapp", "value"); err != nil || n != 42 {
+				t.Errorf("GetAppPropertyInt = %d, %v, want 42", n, err)
+			}
+		})
+	}
+}
+
+func TestSetAppPropertyLargeUnsigned(t *testing.T) {
+	ms := newTestManager(t, "[testapp]\n")
+	ms.SetAppProperty("testapp", "value", uint64(math.MaxUint64))
+
+	if raw, _ := ms.Get("testapp.value"); raw != uint64(math.MaxUint64) {
+		t.Errorf("stored %T(%v), want uint64(%d)", raw, raw, uint64(math.MaxUint64))
+	}
+}
@@ -0,0 +1,49 @@
+package governor
+
+import (
+	"context"
+	"net/http"
+)
+
+// ContextKey is the type of the keys WithContextValue stores request values
+// under, keeping them distinct from other packages' context keys.
+type ContextKey string
+
+// WithContextValue returns middleware which runs extract on each request and
+// stores a non-nil result in the request context under key.  Register it with
+// the API's Use method, e.g.
+//
+//	gapi.Use("tenant", governor.WithContextValue("tenant", governor.FromHeader("X-Tenant-ID")))
+func WithContextValue(key ContextKey, extract func(r *http.Request) interface{}) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if v := extract(r); v != nil {
+				r = r.WithContext(context.WithValue(r.Context(), key, v))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// FromHeader returns an extractor for WithContextValue which reads the named
+// request header, yielding nil when it is absent or empty.
+func FromHeader(name string) func(r *http.Request) interface{} {
+	return func(r *http.Request) interface{} {
+		if v := r.Header.Get(name); v != "" {
+			return v
+		}
+		return nil
+	}
+}
+
+// ContextValue returns the value stored under key by WithContextValue.
+func ContextValue(r *http.Request, key ContextKey) (interface{}, bool) {
+	v := r.Context().Value(key)
+	return v, v != nil
+}
+
+// ContextString returns the value stored under key as a string.
+func ContextString(r *http.Request, key ContextKey) (string, bool) {
+	s, ok := r.Context().Value(key).(string)
+	return s, ok
+}
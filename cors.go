@@ -0,0 +1,139 @@
+package governor
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSMiddleware is the name of the middleware installed by cors_origins, for
+// use with Exempt.
+const CORSMiddleware = "cors"
+
+// CORSOptions configures the CORS middleware.
+type CORSOptions struct {
+	// Origins allowed to make cross-origin requests; "*" allows any.
+	Origins []string
+	// Methods and Headers allowed in preflighted requests.
+	Methods []string
+	Headers []string
+	// AllowCredentials permits cookies and authorization headers.  Only
+	// origins listed explicitly are then allowed, and echoed back, as
+	// credentials must never be offered to any site.
+	AllowCredentials bool
+	// MaxAge lets browsers cache preflight responses.
+	MaxAge time.Duration
+}
+
+// CORS returns middleware which applies opts to cross-origin requests and
+// answers preflight requests directly.
+func CORS(opts CORSOptions) Middleware {
+	methods := strings.Join(opts.Methods, ", ")
+	headers := strings.Join(opts.Headers, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			w.Header().Add("Vary", "Origin")
+
+			allowed, wildcard := opts.allows(origin)
+			if origin == "" || !allowed || wildcard && opts.AllowCredentials {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if wildcard {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			if opts.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// preflight
+			if methods != "" {
+				w.Header().Set("Access-Control-Allow-Methods", methods)
+			}
+			if headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				w.Header().Set("Access-Control-Allow-Headers", requested)
+			}
+			if opts.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge/time.Second)))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// allows reports whether origin is permitted and whether it matched the
+// wildcard.
+func (opts CORSOptions) allows(origin string) (allowed bool, wildcard bool) {
+	for _, o := range opts.Origins {
+		if o == "*" {
+			wildcard = true
+			allowed = true
+		} else if strings.EqualFold(o, origin) {
+			return true, false
+		}
+	}
+	return allowed, wildcard
+}
+
+// configureCORS installs the CORS middleware when cors_origins is configured
+// for app, reading cors_methods, cors_headers, cors_allow_credentials and
+// cors_max_age alongside it.  A "*" origin cannot be combined with
+// cors_allow_credentials.
+func (ms *ManagerService) configureCORS(api *API, app string) error {
+	if _, ok := ms.lookupAppProperty(app, "cors_origins"); !ok {
+		return nil
+	}
+
+	opts := CORSOptions{
+		Methods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+	}
+
+	var err error
+	if opts.Origins, err = ms.GetAppPropertyStrings(app, "cors_origins"); err != nil {
+		return err
+	}
+	if _, ok := ms.lookupAppProperty(app, "cors_methods"); ok {
+		if opts.Methods, err = ms.GetAppPropertyStrings(app, "cors_methods"); err != nil {
+			return err
+		}
+	}
+	if _, ok := ms.lookupAppProperty(app, "cors_headers"); ok {
+		if opts.Headers, err = ms.GetAppPropertyStrings(app, "cors_headers"); err != nil {
+			return err
+		}
+	}
+	if _, ok := ms.lookupAppProperty(app, "cors_allow_credentials"); ok {
+		if opts.AllowCredentials, err = ms.GetAppPropertyBool(app, "cors_allow_credentials"); err != nil {
+			return err
+		}
+	}
+	if opts.AllowCredentials {
+		for _, o := range opts.Origins {
+			if o == "*" {
+				return fmt.Errorf("Configuration 'cors_origins' under [%s] heading must list origins explicitly with cors_allow_credentials set.", app)
+			}
+		}
+	}
+	if _, ok := ms.lookupAppProperty(app, "cors_max_age"); ok {
+		if opts.MaxAge, err = ms.GetAppPropertyDuration(app, "cors_max_age"); err != nil {
+			return err
+		}
+	}
+
+	api.Use(CORSMiddleware, CORS(opts))
+	return nil
+}
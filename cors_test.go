@@ -0,0 +1,108 @@
+package governor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// corsAPI returns testapp configured from the CORS settings in config.
+func corsAPI(t *testing.T, config string) http.Handler {
+	t.Helper()
+	ms := newTestManager(t, "[testapp]\n"+config)
+	api := newTestAPI()
+	if err := ms.configureCORS(api, "testapp"); err != nil {
+		t.Fatalf("configureCORS: %s", err)
+	}
+	api.GET("/thing", func(w http.ResponseWriter, r *http.Request) {})
+	return api.handler()
+}
+
+func TestCORSPreflight(t *testing.T) {
+	h := corsAPI(t, `cors_origins = ["https://app.example.com"]
+cors_methods = ["GET", "POST"]
+cors_headers = ["Content-Type"]
+cors_max_age = "10m"
+`)
+	r := httptest.NewRequest(http.MethodOptions, "/thing", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	r.Header.Set("Access-Control-Request-Method", "POST")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("got %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":  "https://app.example.com",
+		"Access-Control-Allow-Methods": "GET, POST",
+		"Access-Control-Allow-Headers": "Content-Type",
+		"Access-Control-Max-Age":       "600",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Access-Control-Allow-Credentials = %q without cors_allow_credentials", got)
+	}
+}
+
+func TestCORSOrigins(t *testing.T) {
+	cases := []struct {
+		name        string
+		config      string
+		origin      string
+		allowOrigin string
+		credentials string
+	}{
+		{name: "listed", config: `cors_origins = ["https://app.example.com"]`, origin: "https://APP.example.com", allowOrigin: "https://APP.example.com"},
+		{name: "not listed", config: `cors_origins = ["https://app.example.com"]`, origin: "https://evil.example.com"},
+		{name: "wildcard", config: `cors_origins = ["*"]`, origin: "https://any.example.com", allowOrigin: "*"},
+		{name: "credentials listed", config: "cors_origins = [\"https://app.example.com\"]\ncors_allow_credentials = true", origin: "https://app.example.com", allowOrigin: "https://app.example.com", credentials: "true"},
+		{name: "credentials not listed", config: "cors_origins = [\"https://app.example.com\"]\ncors_allow_credentials = true", origin: "https://evil.example.com"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			h := corsAPI(t, c.config+"\n")
+			r := httptest.NewRequest(http.MethodGet, "/thing", nil)
+			r.Header.Set("Origin", c.origin)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("got %d, want %d", rec.Code, http.StatusOK)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != c.allowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, c.allowOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != c.credentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, c.credentials)
+			}
+			if got := rec.Header().Get("Vary"); got != "Origin" {
+				t.Errorf("Vary = %q, want Origin", got)
+			}
+		})
+	}
+}
+
+func TestCORSCredentialsWithWildcard(t *testing.T) {
+	ms := newTestManager(t, "[testapp]\ncors_origins = [\"https://app.example.com\", \"*\"]\ncors_allow_credentials = true\n")
+	err := ms.configureCORS(newTestAPI(), "testapp")
+	if err == nil || !strings.Contains(err.Error(), "'cors_origins' under [testapp] heading") {
+		t.Fatalf("got error %v, want one naming cors_origins", err)
+	}
+
+	// the middleware itself never offers credentials to a wildcard match
+	api := newTestAPI()
+	api.Use(CORSMiddleware, CORS(CORSOptions{Origins: []string{"*"}, AllowCredentials: true}))
+	api.GET("/thing", func(w http.ResponseWriter, r *http.Request) {})
+	r := httptest.NewRequest(http.MethodGet, "/thing", nil)
+	r.Header.Set("Origin", "https://evil.example.com")
+	rec := httptest.NewRecorder()
+	api.handler().ServeHTTP(rec, r)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q for a wildcard with credentials", got)
+	}
+}
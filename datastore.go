@@ -0,0 +1,203 @@
+package governor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lakesite/ls-superbase"
+	"github.com/pelletier/go-toml"
+)
+
+// DatastoreReady reports whether InitDatastore has produced a connection, or
+// a backend store, for app.  It never panics, returning false for unknown
+// apps.
+func (ms *ManagerService) DatastoreReady(app string) bool {
+	ms.dbmu.RLock()
+	defer ms.dbmu.RUnlock()
+
+	if _, ok := ms.stores[app]; ok {
+		return true
+	}
+	dbc := ms.DBConfig[app]
+	return dbc != nil && dbc.Connection != nil
+}
+
+// Apps returns the sorted names of the top-level sections in the
+// configuration.
+func (ms *ManagerService) Apps() []string {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	apps := []string{}
+	if ms.Config == nil {
+		return apps
+	}
+	for _, k := range ms.Config.Keys() {
+		if _, ok := ms.Config.Get(k).(*toml.Tree); ok {
+			apps = append(apps, k)
+		}
+	}
+	sort.Strings(apps)
+	return apps
+}
+
+// InitAllDatastores initializes the datastore of every app with a dbdriver
+// configured.  With failFast it returns the first failure; otherwise it
+// initializes every app it can and returns an error listing those which
+// failed.
+func (ms *ManagerService) InitAllDatastores(failFast bool) error {
+	failed := []string{}
+	for _, app := range ms.Apps() {
+		if _, err := ms.GetAppProperty(app, "dbdriver"); err != nil {
+			continue
+		}
+		if err := ms.initDatastore(app); err != nil {
+			if failFast {
+				return fmt.Errorf("InitAllDatastores: %s", err)
+			}
+			failed = append(failed, err.Error())
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("InitAllDatastores: %d datastore(s) failed: %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// DB returns the gorm connection for app, or an error if the app's datastore
+// has not been initialized.
+func (ms *ManagerService) DB(app string) (*gorm.DB, error) {
+	ms.dbmu.RLock()
+	defer ms.dbmu.RUnlock()
+
+	dbc := ms.DBConfig[app]
+	if dbc == nil || dbc.Connection == nil {
+		return nil, fmt.Errorf("Datastore for '%s' has not been initialized.", app)
+	}
+	return dbc.Connection, nil
+}
+
+// warnUnsupportedOptions logs datastore options for app which the underlying
+// jinzhu/gorm driver used by superbase cannot honour.
+func (ms *ManagerService) warnUnsupportedOptions(app string) {
+	// db_prepare_stmt maps to gorm v2's PrepareStmt, which jinzhu/gorm lacks.
+	// Leaving it off is also the safe choice behind PgBouncer in transaction
+	// pooling mode, where cached prepared statements break across backends.
+	if prepare, err := ms.GetAppPropertyBool(app, "db_prepare_stmt"); err == nil && prepare {
+		ms.AppLog(app).Warnf("InitDatastore: db_prepare_stmt under [%s] is not supported by the gorm driver and is ignored.", app)
+	}
+}
+
+// prepareSQLitePath expands environment variables such as ${DATA_DIR} in a
+// sqlite3 dbpath and creates its parent directory if needed.
+func prepareSQLitePath(dbc *superbase.DBConfig) error {
+	if dbc.Driver != "sqlite3" || dbc.Path == "" {
+		return nil
+	}
+
+	dbc.Path = os.ExpandEnv(dbc.Path)
+	if dbc.Path == ":memory:" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dbc.Path), 0755); err != nil {
+		return fmt.Errorf("Unable to create directory for dbpath '%s': %s", dbc.Path, err)
+	}
+	return nil
+}
+
+// sessionOptions are the gorm behaviours an app may tune in config.
+type sessionOptions struct {
+	// skipTransaction drops the transaction gorm wraps around each create,
+	// update and delete
+	skipTransaction bool
+	// saveAssociations, when set, overrides whether gorm saves associations
+	saveAssociations *bool
+}
+
+// sessionOptions reads db_skip_default_transaction and
+// db_full_save_associations for app.  jinzhu/gorm has no Session or Config
+// type, so these map onto its callbacks and the gorm:save_associations
+// setting instead.
+func (ms *ManagerService) sessionOptions(app string) (sessionOptions, error) {
+	var opts sessionOptions
+
+	if _, ok := ms.lookupAppProperty(app, "db_skip_default_transaction"); ok {
+		skip, err := ms.GetAppPropertyBool(app, "db_skip_default_transaction")
+		if err != nil {
+			return opts, err
+		}
+		opts.skipTransaction = skip
+	}
+	if _, ok := ms.lookupAppProperty(app, "db_full_save_associations"); ok {
+		save, err := ms.GetAppPropertyBool(app, "db_full_save_associations")
+		if err != nil {
+			return opts, err
+		}
+		opts.saveAssociations = &save
+	}
+	return opts, nil
+}
+
+// skipDefaultTransaction removes the callbacks with which gorm wraps writes
+// in a transaction.  Callbacks belong to the connection, so this applies to
+// every handle sharing it.
+func skipDefaultTransaction(conn *gorm.DB) {
+	for _, cp := range []*gorm.CallbackProcessor{conn.Callback().Create(), conn.Callback().Update(), conn.Callback().Delete()} {
+		cp.Remove("gorm:begin_transaction")
+		cp.Remove("gorm:commit_or_rollback_transaction")
+	}
+}
+
+// connParams returns the extra connection params for app's datastore.
+// Postgres connections with params, from dburl or db_statement_timeout, are
+// opened by governor so they reach the server; everything else is left to
+// superbase and gets none.
+func (ms *ManagerService) connParams(app string, dbc *superbase.DBConfig, params map[string]string) (map[string]string, error) {
+	if dbc.Driver != "postgres" {
+		params = map[string]string{}
+	}
+
+	if _, ok := ms.lookupAppProperty(app, "db_statement_timeout"); ok {
+		timeout, err := ms.GetAppPropertyDuration(app, "db_statement_timeout")
+		if err != nil {
+			return nil, err
+		}
+		if dbc.Driver != "postgres" {
+			ms.AppLog(app).Debugf("InitDatastore: db_statement_timeout under [%s] only applies to postgres and is ignored.", app)
+		} else {
+			params["statement_timeout"] = strconv.FormatInt(int64(timeout/time.Millisecond), 10)
+		}
+	}
+
+	if len(params) == 0 {
+		return params, nil
+	}
+
+	if sslmode, err := ms.GetAppProperty(app, "dbsslmode"); err == nil {
+		params["sslmode"] = sslmode
+	}
+	return params, nil
+}
+
+// openDatastore connects dbc, through superbase unless there are params from
+// connParams.
+func openDatastore(dbc *superbase.DBConfig, params map[string]string) error {
+	if len(params) == 0 {
+		dbc.Init()
+		return nil
+	}
+
+	conn, err := gorm.Open(dbc.Driver, postgresDSN(dbc, params))
+	if err != nil {
+		return err
+	}
+	dbc.Connection = conn
+	return nil
+}
@@ -0,0 +1,41 @@
+package governor
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// SetDBErrorHandler registers fn to observe every error returned by a
+// database operation run through governor's helpers, such as Transaction,
+// WithRetry and the migration functions, e.g. to log or count them.  fn is
+// called synchronously with the app and the error, which IsTransientDBError
+// can classify.  Passing nil removes the handler.
+func (ms *ManagerService) SetDBErrorHandler(fn func(app string, err error)) {
+	ms.dbErrorHandler.Store(dbErrorHolder{fn})
+}
+
+// dbErrorHolder wraps the handler so atomic.Value always stores one type.
+type dbErrorHolder struct {
+	fn func(app string, err error)
+}
+
+// dbError passes a non-nil err from one of app's database operations to the
+// error handler and returns it.
+func (ms *ManagerService) dbError(app string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if h, ok := ms.dbErrorHandler.Load().(dbErrorHolder); ok && h.fn != nil {
+		h.fn(app, err)
+	}
+	return err
+}
+
+// Transaction runs fn in a transaction on app's datastore, committing when it
+// returns nil and rolling back when it returns an error or panics.
+func (ms *ManagerService) Transaction(app string, fn func(tx *gorm.DB) error) error {
+	db, err := ms.DB(app)
+	if err != nil {
+		return err
+	}
+	return ms.dbError(app, db.Transaction(fn))
+}
@@ -0,0 +1,91 @@
+package governor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// decodeLimit caps the request bodies DecodeJSON reads.
+const decodeLimit = 1 << 20
+
+// The kinds of DecodeError, for use with errors.Is.
+var (
+	ErrEmptyBody     = errors.New("Request body is empty.")
+	ErrBodyTooLarge  = errors.New("Request body is too large.")
+	ErrMalformedJSON = errors.New("Request body is not valid JSON.")
+	ErrUnknownField  = errors.New("Request body has an unknown field.")
+)
+
+// DecodeError describes why DecodeJSON rejected a request body.  Status is
+// the HTTP status to answer with, so handlers can reply with
+//
+//	governor.Respond(w, r, derr.Status, derr)
+type DecodeError struct {
+	Status int
+	Err    error
+	Detail string
+}
+
+func (e *DecodeError) Error() string {
+	if e.Detail == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s %s", e.Err, e.Detail)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeJSON decodes the JSON request body into out, rejecting bodies over
+// 1MiB, empty bodies, malformed JSON, trailing data and fields out does not
+// define.  Failures are returned as a *DecodeError.
+func DecodeJSON(r *http.Request, out interface{}) error {
+	if r.Body == nil {
+		return &DecodeError{Status: http.StatusBadRequest, Err: ErrEmptyBody}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, decodeLimit+1))
+	if err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			return &DecodeError{Status: http.StatusRequestEntityTooLarge, Err: ErrBodyTooLarge}
+		}
+		return &DecodeError{Status: http.StatusBadRequest, Err: ErrMalformedJSON, Detail: err.Error()}
+	}
+	if len(body) > decodeLimit {
+		return &DecodeError{Status: http.StatusRequestEntityTooLarge, Err: ErrBodyTooLarge}
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return &DecodeError{Status: http.StatusBadRequest, Err: ErrEmptyBody}
+	}
+
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.DisallowUnknownFields()
+	if err := d.Decode(out); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		var syntaxErr *json.SyntaxError
+		switch {
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+			return &DecodeError{Status: http.StatusBadRequest, Err: ErrUnknownField, Detail: "Field " + field + " is not accepted."}
+		case errors.As(err, &typeErr):
+			detail := fmt.Sprintf("Field '%s' must be %s.", typeErr.Field, typeErr.Type)
+			return &DecodeError{Status: http.StatusBadRequest, Err: ErrMalformedJSON, Detail: detail}
+		case errors.As(err, &syntaxErr):
+			detail := fmt.Sprintf("Syntax error at offset %d.", syntaxErr.Offset)
+			return &DecodeError{Status: http.StatusBadRequest, Err: ErrMalformedJSON, Detail: detail}
+		}
+		return &DecodeError{Status: http.StatusBadRequest, Err: ErrMalformedJSON, Detail: err.Error()}
+	}
+	// a stray closing } or ] after the value is not caught by More
+	if err := d.Decode(&struct{}{}); err != io.EOF {
+		return &DecodeError{Status: http.StatusBadRequest, Err: ErrMalformedJSON, Detail: "Unexpected data after the JSON value."}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package governor
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSONTrailingData(t *testing.T) {
+	cases := []struct {
+		body string
+		ok   bool
+	}{
+		{`{"a":1}`, true},
+		{"{\"a\":1}\n \t", true},
+		{`{"a":1}}`, false},
+		{`{"a":1}]`, false},
+		{`{"a":1} {"b":2}`, false},
+		{`{"a":1} x`, false},
+	}
+	for _, c := range cases {
+		t.Run(c.body, func(t *testing.T) {
+			var out struct {
+				A int `json:"a"`
+			}
+			err := DecodeJSON(httptest.NewRequest("POST", "/", strings.NewReader(c.body)), &out)
+			switch {
+			case c.ok && err != nil:
+				t.Fatalf("unexpected error: %s", err)
+			case !c.ok && !errors.Is(err, ErrMalformedJSON):
+				t.Fatalf("got error %v, want ErrMalformedJSON", err)
+			}
+			if out.A != 1 {
+				t.Errorf("a = %d, want 1", out.A)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package governor
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// defaultDrainPeriod is how long the API drains before shutting down when
+// drain_period is not configured.
+const defaultDrainPeriod = 10 * time.Second
+
+// Drain takes the API out of rotation: the readiness endpoint starts
+// returning 503 while in-flight and new requests continue to be served, and
+// once the drain period has elapsed Daemonize proceeds with graceful shutdown.
+// Calling Drain more than once has no further effect.
+func (api *API) Drain() {
+	if !atomic.CompareAndSwapInt32(&api.draining, 0, 1) {
+		return
+	}
+	time.AfterFunc(api.drainPeriod, func() {
+		close(api.drained)
+	})
+}
+
+// Draining reports whether Drain has been called.
+func (api *API) Draining() bool {
+	return atomic.LoadInt32(&api.draining) == 1
+}
+
+// drainHandler starts draining the API.
+func (api *API) drainHandler(w http.ResponseWriter, r *http.Request) {
+	api.Drain()
+	api.WebService.JsonStatusResponse(w, "Draining", http.StatusAccepted)
+}
+
+// configureDrain reads drain_period for app and, when basic auth is
+// configured, mounts POST /admin/drain behind it.
+func (ms *ManagerService) configureDrain(api *API, app string) error {
+	api.drainPeriod = defaultDrainPeriod
+	if _, ok := ms.lookupAppProperty(app, "drain_period"); ok {
+		d, err := ms.GetAppPropertyDuration(app, "drain_period")
+		if err != nil {
+			return err
+		}
+		api.drainPeriod = d
+	}
+
+	if auth := ms.adminAuth(app); auth != nil {
+		api.POST("/admin/drain", auth(http.HandlerFunc(api.drainHandler)).ServeHTTP)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package governor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// drainTestAPI starts an API for config which also serves GET /work.
+func drainTestAPI(t *testing.T, config string) (*API, string) {
+	t.Helper()
+
+	ms := newTestManager(t, "[testapp]\nport = 0\n"+config)
+	api, err := ms.createAPI("testapp")
+	if err != nil {
+		t.Fatalf("createAPI: %s", err)
+	}
+	api.GET("/work", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	if err := api.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		api.Shutdown(ctx)
+	})
+	return api, "http://" + api.BoundAddr().String()
+}
+
+func statusOf(t *testing.T, method string, url string, user string) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user != "" {
+		req.SetBasicAuth(user, "secret")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %s", method, url, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestDrainEndpoint(t *testing.T) {
+	api, base := drainTestAPI(t, "basic_auth_user = \"admin\"\nbasic_auth_password = \"secret\"\ndrain_period = \"300ms\"\n")
+
+	if got := statusOf(t, "GET", base+"/readyz", ""); got != http.StatusOK {
+		t.Fatalf("/readyz before draining = %d, want 200", got)
+	}
+	if got := statusOf(t, "POST", base+"/admin/drain", ""); got != http.StatusUnauthorized {
+		t.Errorf("unauthenticated drain = %d, want 401", got)
+	}
+	if api.Draining() {
+		t.Fatal("an unauthenticated request started draining")
+	}
+
+	if got := statusOf(t, "POST", base+"/admin/drain", "admin"); got != http.StatusAccepted {
+		t.Fatalf("drain = %d, want 202", got)
+	}
+	if got := statusOf(t, "GET", base+"/readyz", ""); got != http.StatusServiceUnavailable {
+		t.Errorf("/readyz while draining = %d, want 503", got)
+	}
+	if got := statusOf(t, "GET", base+"/work", ""); got != http.StatusNoContent {
+		t.Errorf("/work while draining = %d, want it still served", got)
+	}
+	// a second drain does not restart the period
+	if got := statusOf(t, "POST", base+"/admin/drain", "admin"); got != http.StatusAccepted {
+		t.Errorf("second drain = %d, want 202", got)
+	}
+
+	select {
+	case err := <-api.served:
+		if err != nil {
+			t.Errorf("served: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the API was still serving after the drain period")
+	}
+}
+
+func TestDrainEndpointNeedsAuth(t *testing.T) {
+	api, base := drainTestAPI(t, "")
+
+	if got := statusOf(t, "POST", base+"/admin/drain", ""); got != http.StatusNotFound {
+		t.Errorf("drain without basic auth configured = %d, want 404", got)
+	}
+	if api.Draining() {
+		t.Error("draining without the endpoint mounted")
+	}
+}
+
+func TestDrainPeriodInvalid(t *testing.T) {
+	ms := newTestManager(t, "[testapp]\nport = 0\ndrain_period = \"soon\"\n")
+	if _, err := ms.createAPI("testapp"); err == nil || !strings.Contains(err.Error(), "drain_period") {
+		t.Errorf("got error %v, want one naming drain_period", err)
+	}
+}
@@ -0,0 +1,62 @@
+package governor
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/lakesite/ls-superbase"
+)
+
+// postgresDSN builds a libpq key/value connection string for dbc.  Additional
+// params are appended after the connection settings; lib/pq forwards keys it
+// does not recognise, such as statement_timeout, to the server as run-time
+// parameters.
+func postgresDSN(dbc *superbase.DBConfig, params map[string]string) string {
+	pairs := []string{}
+	add := func(key, value string) {
+		if value != "" {
+			pairs = append(pairs, key+"="+quoteDSNValue(value))
+		}
+	}
+
+	add("host", dbc.Server)
+	add("port", dbc.Port)
+	add("user", dbc.User)
+	add("dbname", dbc.Database)
+	add("password", dbc.Password)
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		add(k, params[k])
+	}
+
+	return strings.Join(pairs, " ")
+}
+
+// redactedDSN describes the connection dbc makes, for logging, with the
+// password masked.  sqlite3 datastores are described by their path.
+func redactedDSN(dbc *superbase.DBConfig, params map[string]string) string {
+	if dbc.Driver == "sqlite3" {
+		return dbc.Driver + " " + dbc.Path
+	}
+
+	masked := *dbc
+	if masked.Password != "" {
+		masked.Password = redacted
+	}
+	return dbc.Driver + " " + postgresDSN(&masked, params)
+}
+
+// quoteDSNValue quotes value for a libpq connection string when needed.
+func quoteDSNValue(value string) string {
+	if !strings.ContainsAny(value, " '\\") {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
@@ -0,0 +1,70 @@
+package governor
+
+import (
+	"github.com/pelletier/go-toml"
+)
+
+// WithEnvironment returns a view of the manager which resolves app
+// properties from the app's [app.env] section first, falling back to [app],
+// so one process can serve several environments or tenants:
+//
+//	[example_app]
+//	dbpath = "example.db"
+//
+//	[example_app.staging]
+//	dbpath = "staging.db"
+//
+// APPNAME_PROPERTY environment variables still take precedence.  The view
+// follows reloads of the original, which it never modifies: SetAppProperty
+// on the view only affects the view.  It is meant for resolving
+// configuration (GetAppProperty and the typed getters, GetAppSection,
+// UnmarshalAppConfig and Feature); datastores and servers belong to the
+// original manager.
+func (ms *ManagerService) WithEnvironment(env string) *ManagerService {
+	if ms.parent != nil {
+		// views of views resolve against the root manager
+		ms = ms.parent
+	}
+	return &ManagerService{parent: ms, env: env}
+}
+
+// envSection returns the [app.env] section of a WithEnvironment view, if the
+// view has an environment and the section exists.
+func (ms *ManagerService) envSection(app string) (map[string]interface{}, bool) {
+	if ms.env == "" {
+		return nil, false
+	}
+	return ms.getTable(app + "." + ms.env)
+}
+
+// getTable returns the table at path as a map, and false when path is not a
+// table.  A view's own table, holding its SetAppProperty values, is merged
+// over its parent's, so setting one key does not hide the rest.
+func (ms *ManagerService) getTable(path string) (map[string]interface{}, bool) {
+	ms.mu.RLock()
+	var own interface{}
+	if ms.Config != nil {
+		own = ms.Config.Get(path)
+	}
+	ms.mu.RUnlock()
+
+	if own == nil {
+		if ms.parent != nil {
+			return ms.parent.getTable(path)
+		}
+		return nil, false
+	}
+	tree, ok := own.(*toml.Tree)
+	if !ok {
+		return nil, false
+	}
+
+	table := map[string]interface{}{}
+	if ms.parent != nil {
+		if inherited, ok := ms.parent.getTable(path); ok {
+			table = inherited
+		}
+	}
+	mergeMaps(table, tree.ToMap())
+	return table, true
+}
@@ -0,0 +1,71 @@
+package governor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithEnvironmentSetAppProperty(t *testing.T) {
+	ms := newTestManager(t, `[testapp]
+a = "s"
+b = 2
+
+[testapp.nested]
+x = 1
+y = 2
+
+[testapp.staging]
+dbpath = "staging.db"
+`)
+	view := ms.WithEnvironment("staging")
+	view.SetAppProperty("testapp", "c", "3")
+	view.SetAppProperty("testapp", "nested.y", 3)
+	view.SetAppProperty("testapp", "staging.extra", "e")
+
+	section, err := view.GetAppSection("testapp")
+	if err != nil {
+		t.Fatalf("GetAppSection: %s", err)
+	}
+	want := map[string]interface{}{
+		"a":      "s",
+		"b":      int64(2),
+		"c":      "3",
+		"nested": map[string]interface{}{"x": int64(1), "y": int64(3)},
+		"dbpath": "staging.db",
+		"extra":  "e",
+	}
+	if !reflect.DeepEqual(section, want) {
+		t.Errorf("view section = %v, want %v", section, want)
+	}
+	if got, err := view.GetAppProperty("testapp", "dbpath"); err != nil || got != "staging.db" {
+		t.Errorf("view dbpath = %q, %v, want staging.db", got, err)
+	}
+
+	// the original is untouched
+	section, err = ms.GetAppSection("testapp")
+	if err != nil {
+		t.Fatalf("GetAppSection: %s", err)
+	}
+	if _, ok := section["c"]; ok {
+		t.Errorf("original section has the view's c: %v", section)
+	}
+	if got := section["nested"].(map[string]interface{})["y"]; got != int64(2) {
+		t.Errorf("original nested.y = %v, want 2", got)
+	}
+}
+
+func TestWithEnvironmentSectionErrors(t *testing.T) {
+	ms := newTestManager(t, "top = 1\n\n[testapp]\na = 1\n")
+	view := ms.WithEnvironment("staging")
+	view.SetAppProperty("other", "a", 1)
+
+	if _, err := view.GetAppSection("missing"); err == nil || err.Error() != "Configuration missing [missing] heading." {
+		t.Errorf("missing: got %v", err)
+	}
+	if _, err := view.GetAppSection("top"); err == nil || err.Error() != "Configuration 'top' is not a section." {
+		t.Errorf("top: got %v", err)
+	}
+	if section, err := view.GetAppSection("other"); err != nil || section["a"] != int64(1) {
+		t.Errorf("other = %v, %v, want the view's own section", section, err)
+	}
+}
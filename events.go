@@ -0,0 +1,55 @@
+package governor
+
+import (
+	"time"
+)
+
+// EventType identifies a lifecycle event.
+type EventType string
+
+const (
+	EventConfigLoaded       EventType = "config_loaded"
+	EventDatastoreConnected EventType = "datastore_connected"
+	EventServerListening    EventType = "server_listening"
+	EventShutdownInitiated  EventType = "shutdown_initiated"
+	EventShutdownComplete   EventType = "shutdown_complete"
+)
+
+// Event describes a lifecycle event emitted by the manager.  App is empty for
+// events which are not specific to an app, and Err is set when the step the
+// event reports failed.
+type Event struct {
+	Type EventType
+	App  string
+	Time time.Time
+	Err  error
+}
+
+// OnEvent subscribes fn to the manager's lifecycle events.  Subscribers are
+// called synchronously, in the order they subscribed, so they should return
+// quickly.
+func (ms *ManagerService) OnEvent(fn func(Event)) {
+	ms.eventsMu.Lock()
+	defer ms.eventsMu.Unlock()
+
+	ms.subscribers = append(ms.subscribers, fn)
+}
+
+// emit logs an event and delivers it to every subscriber.
+func (ms *ManagerService) emit(t EventType, app string, err error) {
+	e := Event{Type: t, App: app, Time: ms.now(), Err: err}
+	if err != nil {
+		ms.AppLog(app).Debugf("Event %s [%s]: %s", t, app, err)
+	} else {
+		ms.AppLog(app).Debugf("Event %s [%s]", t, app)
+	}
+
+	ms.eventsMu.RLock()
+	subscribers := make([]func(Event), len(ms.subscribers))
+	copy(subscribers, ms.subscribers)
+	ms.eventsMu.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(e)
+	}
+}
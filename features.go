@@ -0,0 +1,47 @@
+package governor
+
+// Feature reports whether the boolean flag is enabled for app.  The value is
+// read once with GetAppPropertyBool and cached until the configuration is
+// reloaded; missing or non-boolean flags are disabled.  It is safe for
+// concurrent use from request handlers.
+func (ms *ManagerService) Feature(app string, flag string) bool {
+	if ms.parent != nil {
+		// views are not told about reloads, so they don't cache
+		enabled, _ := ms.GetAppPropertyBool(app, flag)
+		return enabled
+	}
+
+	key := app + "." + flag
+
+	ms.featuresMu.RLock()
+	enabled, ok := ms.features[key]
+	generation := ms.featuresGen
+	ms.featuresMu.RUnlock()
+	if ok {
+		return enabled
+	}
+
+	enabled, _ = ms.GetAppPropertyBool(app, flag)
+
+	// a reload during the read may have made the value stale, so it is only
+	// cached if the cache has not been reset since
+	ms.featuresMu.Lock()
+	if ms.featuresGen == generation {
+		if ms.features == nil {
+			ms.features = make(map[string]bool)
+		}
+		ms.features[key] = enabled
+	}
+	ms.featuresMu.Unlock()
+
+	return enabled
+}
+
+// resetFeatures clears the feature flag cache, starting a new generation so
+// reads begun before the reset are not cached.
+func (ms *ManagerService) resetFeatures() {
+	ms.featuresMu.Lock()
+	ms.features = nil
+	ms.featuresGen++
+	ms.featuresMu.Unlock()
+}
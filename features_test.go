@@ -0,0 +1,40 @@
+package governor
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestFeatureCacheReset(t *testing.T) {
+	ms := newTestManager(t, "[testapp]\nbeta = false\n")
+	if ms.Feature("testapp", "beta") {
+		t.Fatal("beta enabled before it was set")
+	}
+	ms.SetAppProperty("testapp", "beta", true)
+	if !ms.Feature("testapp", "beta") {
+		t.Fatal("beta still cached as disabled after SetAppProperty")
+	}
+}
+
+func TestFeatureConcurrentReset(t *testing.T) {
+	ms := newTestManager(t, "[testapp]\nbeta = false\n")
+
+	// readers racing the writer must never leave a stale value cached
+	for round := 0; round < 50; round++ {
+		want := round%2 == 0
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ms.Feature("testapp", "beta")
+			}()
+		}
+		ms.SetAppProperty("testapp", "beta", want)
+		wg.Wait()
+
+		if got := ms.Feature("testapp", "beta"); got != want {
+			t.Fatalf("round %d: got %v after the last reset, want %v", round, got, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package governor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v2"
+)
+
+// formatForPath picks the config format from path's extension, defaulting to
+// TOML.
+func formatForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".json":
+		return "json"
+	}
+	return "toml"
+}
+
+// loadConfigFormat loads path as format ("toml", "yaml" or "json"),
+// returning the tree and the files read.  Includes are only processed for
+// TOML.
+func loadConfigFormat(path string, format string, opts loadOptions) (*toml.Tree, []string, error) {
+	format = strings.ToLower(format)
+	switch format {
+	case "toml":
+		return loadConfigFile(path, opts)
+	case "yaml", "yml", "json":
+	default:
+		return nil, nil, fmt.Errorf("Unknown config format '%s'.", format)
+	}
+
+	data, err := readFile(path, opts.timeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to load '%s': %w", path, err)
+	}
+
+	var raw interface{}
+	if format == "json" {
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.UseNumber()
+		err = d.Decode(&raw)
+	} else {
+		err = yaml.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to load '%s': %s", path, err)
+	}
+
+	normalized, err := normalizeValue("", raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to load '%s': %s", path, err)
+	}
+	m, ok := normalized.(map[string]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("Unable to load '%s': top level must be a mapping.", path)
+	}
+	tree, err := treeFromMap(m)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to load '%s': %s", path, err)
+	}
+
+	source := path
+	if abs, err := filepath.Abs(path); err == nil {
+		source = abs
+	}
+	return tree, []string{source}, nil
+}
+
+// normalizeValue converts decoded YAML and JSON into the types a TOML tree
+// holds: string keyed maps, int64 integers, float64 floats and arrays of
+// tables for lists of mappings.  Null values and integers beyond int64 have
+// no TOML equivalent and are reported with their dotted key.
+func normalizeValue(key string, v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case nil:
+		return nil, fmt.Errorf("'%s' is null.", key)
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			name := fmt.Sprint(k)
+			n, err := normalizeValue(dottedKey(key, name), e)
+			if err != nil {
+				return nil, err
+			}
+			m[name] = n
+		}
+		return m, nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			n, err := normalizeValue(dottedKey(key, k), e)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = n
+		}
+		return m, nil
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		tables := len(t) > 0
+		for i, e := range t {
+			n, err := normalizeValue(fmt.Sprintf("%s[%d]", key, i), e)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = n
+			if _, ok := n.(map[string]interface{}); !ok {
+				tables = false
+			}
+		}
+		if tables {
+			maps := make([]map[string]interface{}, len(out))
+			for i, e := range out {
+				maps[i] = e.(map[string]interface{})
+			}
+			return maps, nil
+		}
+		return out, nil
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a valid number.", key)
+		}
+		return f, nil
+	case int:
+		return int64(t), nil
+	case uint64:
+		if t > math.MaxInt64 {
+			return nil, fmt.Errorf("'%s' is too large.", key)
+		}
+		return int64(t), nil
+	case float32:
+		return float64(t), nil
+	}
+	return v, nil
+}
+
+// dottedKey returns k under prefix, or k alone at the top level.
+func dottedKey(prefix string, k string) string {
+	if prefix == "" {
+		return k
+	}
+	return prefix + "." + k
+}
+
+// InitManagerFormat reads configuration from path in the given format
+// ("toml", "yaml" or "json") regardless of its extension, which suits mounted
+// config files such as Kubernetes ConfigMap keys.
+func (ms *ManagerService) InitManagerFormat(path string, format string) error {
+	return ms.InitManagerProvider(NewFileProvider(path, format))
+}
@@ -0,0 +1,49 @@
+package governor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitManagerFormatErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		file    string
+		data    string
+		wantErr string
+	}{
+		{name: "yaml mixed array", file: "config.yaml", data: "testapp:\n  list: [1, \"a\"]\n", wantErr: "Unable to load"},
+		{name: "json mixed array", file: "config.json", data: `{"testapp": {"list": [1, "a"]}}`, wantErr: "Unable to load"},
+		{name: "yaml too large", file: "config.yaml", data: "testapp:\n  big: 18446744073709551615\n", wantErr: "'testapp.big' is too large"},
+		{name: "yaml null", file: "config.yaml", data: "testapp:\n  dbpath: null\n", wantErr: "'testapp.dbpath' is null"},
+		{name: "json null in array", file: "config.json", data: `{"testapp": {"list": [1, null]}}`, wantErr: "'testapp.list[1]' is null"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), c.file)
+			if err := os.WriteFile(path, []byte(c.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := (&ManagerService{}).InitManager(path)
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Fatalf("got error %v, want one containing %q", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestInitManagerFormatLargestInteger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("testapp:\n  big: 9223372036854775807\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ms := &ManagerService{}
+	if err := ms.InitManager(path); err != nil {
+		t.Fatalf("InitManager: %s", err)
+	}
+	if v, _ := ms.Get("testapp.big"); v != int64(9223372036854775807) {
+		t.Errorf("big = %T(%v), want the largest int64", v, v)
+	}
+}
@@ -3,11 +3,22 @@
 package governor
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
-	"log"
+	"io"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/lakesite/ls-config"
 	"github.com/lakesite/ls-fibre"
 	"github.com/lakesite/ls-superbase"
@@ -18,68 +29,383 @@ import (
 type API struct {
 	WebService *fibre.WebService
 	ManagerService *ManagerService
+
+	// Address is the host:port the web service listens on.
+	Address string
+	app     string
+
+	// TLSConfig, when set, causes Daemonize to serve HTTPS.
+	TLSConfig *tls.Config
+	tlsCert   string
+	tlsKey    string
+
+	// redirectAddr, when set, serves HTTP redirects to the HTTPS listener.
+	// challenge, when set, wraps the redirect handler to answer ACME
+	// challenges.
+	redirectAddr string
+	challenge    func(fallback http.Handler) http.Handler
+
+	middleware []namedMiddleware
+	exempt     map[string][]string
+
+	// auth is applied to routes registered with RequireAuth
+	auth Middleware
+
+	// basePath prefixes every route, see basepath.go
+	basePath string
+
+	routesMu sync.Mutex
+	routes   []RouteInfo
+	streams  map[*mux.Route]bool
+
+	// onServer hooks adjust the http.Server before it listens
+	onServer []func(*http.Server)
+
+	// primary and redirect listeners, handed over on graceful restart
+	lnMu            sync.Mutex
+	ln              net.Listener
+	redirectLn      net.Listener
+	listening       chan struct{}
+	listenOnce      sync.Once
+	gracefulRestart bool
+
+	// Start and Shutdown state, and the context cancelled when the API's
+	// shutdown begins, see lifecycle.go
+	lifeMu sync.Mutex
+	stop   context.CancelFunc
+	served chan error
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// how long in-flight requests get once shutdown begins, see lifecycle.go
+	shutdownTimeout  time.Duration
+	interruptTimeout time.Duration
+
+	// health endpoint state, see health.go
+	healthTimeout  time.Duration
+	healthInterval time.Duration
+	healthMu      sync.RWMutex
+	lastReport    *healthReport
+	lastStatus    int
+
+	// drain state, see drain.go
+	draining    int32
+	drained     chan struct{}
+	drainPeriod time.Duration
 }
 
 func NewAPI(ws *fibre.WebService, ms *ManagerService) *API {
-	return &API{WebService: ws, ManagerService: ms}
+	return &API{
+		WebService:       ws,
+		ManagerService:   ms,
+		drained:          make(chan struct{}),
+		drainPeriod:      defaultDrainPeriod,
+		shutdownTimeout:  defaultShutdownTimeout,
+		interruptTimeout: defaultInterruptTimeout,
+		listening:        make(chan struct{}),
+	}
 }
 
 // ManagerService contains the configuration settings required to manage the api.
 type ManagerService struct {
 	Config   *toml.Tree
 	DBConfig map[string]*superbase.DBConfig
+
+	// parent and env are set on WithEnvironment views, see environment.go
+	parent *ManagerService
+	env    string
+
+	// mu guards Config against concurrent reloads.
+	mu       sync.RWMutex
+	provider ConfigProvider
+	sources  []string
+
+	// dbmu guards DBConfig, the shared pools and the backend stores, see
+	// pool.go and backends.go
+	dbmu     sync.RWMutex
+	pools    map[string]*sharedPool
+	appPools map[string]*sharedPool
+	backends map[string]func(map[string]interface{}) (io.Closer, error)
+	stores   map[string]io.Closer
+
+	// feature flag cache, see features.go
+	featuresMu  sync.RWMutex
+	features    map[string]bool
+	featuresGen uint64
+
+	healthMu     sync.RWMutex
+	healthChecks map[string][]namedCheck
+
+	eventsMu    sync.RWMutex
+	subscribers []func(Event)
+
+	// scheduled tasks, see tasks.go
+	tasksMu      sync.Mutex
+	tasks        []*task
+	tasksStarted bool
+
+	// request and datastore metrics, see metrics.go
+	metricsOnce sync.Once
+	metrics     *metricsRegistry
+
+	// registered models and migrations, see migrations.go
+	migrationsMu sync.Mutex
+	migrations   map[string]*appMigrations
+
+	// config value resolvers, see interpolate.go
+	resolversMu sync.RWMutex
+	resolvers   map[string]Resolver
+
+	// configSubscribers observe reloads, see reload.go
+	configSubscribers []func(ConfigChange)
+
+	// dbErrorHandler observes database errors, see dberrors.go
+	dbErrorHandler atomic.Value
+
+	// clock is the time source, see clock.go
+	clock atomic.Value
+
+	// logging, see logger.go
+	logger       atomic.Value
+	logLevel     int32
+	logJSON      int32
+	appLogLevels atomic.Value
+	appLogJSON   atomic.Value
+
+	// readTimeout bounds config file reads, see readfile.go
+	readTimeout int64
+
+	// lifecycle state, see lifecycle.go
+	lifeMu  sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running int
+	active  int
+	workers sync.WaitGroup
 }
 
 // GetAppProperty gets the property for app as a string, if property does not 
-// exist return err.
+// exist return err.  An APPNAME_PROPERTY environment variable takes precedence
+// over the configuration file.  Placeholders such as "${env:DB_PASSWORD}"
+// are resolved, returning an error if they cannot be.
 func (ms *ManagerService) GetAppProperty(app string, property string) (string, error) {
-	if ms.Config.Get(app+"."+property) != nil {
-		return ms.Config.Get(app + "." + property).(string), nil
-	} else {
+	value, ok, err := ms.resolveAppProperty(app, property)
+	if !ok {
 		return "", fmt.Errorf("Configuration missing '%s' section under [%s] heading.\n", property, app)
 	}
+	if err != nil {
+		return "", err
+	}
+
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return "", fmt.Errorf("Configuration '%s' under [%s] heading is not a string.\n", property, app)
 }
 
-// InitDatastore initializes the datastore by app name
-// return true if successful false otherwise
-func (ms *ManagerService) InitDatastore(app string) bool {
-	if ms.DBConfig[app] == nil {
-		ms.DBConfig[app] = &superbase.DBConfig{}
+// InitDatastore initializes the datastore by app name, returning an error
+// naming any required settings the app's driver is missing or the reason
+// the connection failed.  An app whose datastore is already initialized and
+// reachable is left untouched; use ReinitDatastore to force a new
+// connection.
+func (ms *ManagerService) InitDatastore(app string) error {
+	if err := ms.initDatastore(app); err != nil {
+		return fmt.Errorf("InitDatastore: %s", err)
 	}
+	return nil
+}
 
-	success := true
+// ReinitDatastore initializes a fresh connection for app from the current
+// configuration and then closes the one it replaces; if that fails the
+// existing connection is kept.  While other apps on the same database still
+// hold its shared pool, a reachable pool is rejoined rather than reopened.
+func (ms *ManagerService) ReinitDatastore(app string) error {
+	var err error
+	if initFn, ok := ms.backend(app); ok {
+		err = ms.initBackend(app, initFn, true)
+	} else {
+		err = ms.connectDatastore(app)
+	}
+	if err != nil {
+		return fmt.Errorf("ReinitDatastore: %s", err)
+	}
+	return nil
+}
 
-	// pull in the database config to DBConfig struct
-	ms.DBConfig[app].Server, _ = ms.GetAppProperty(app, "dbserver")
-	ms.DBConfig[app].Port, _ = ms.GetAppProperty(app, "dbport")
-	ms.DBConfig[app].Database, _ = ms.GetAppProperty(app, "database")
-	ms.DBConfig[app].User, _ = ms.GetAppProperty(app, "dbuser")
-	ms.DBConfig[app].Password, _ = ms.GetAppProperty(app, "dbpassword")
-	ms.DBConfig[app].Driver, _ = ms.GetAppProperty(app, "dbdriver")
-	ms.DBConfig[app].Path, _ = ms.GetAppProperty(app, "dbpath")
+// initDatastore connects app's datastore unless an existing connection is
+// still reachable.
+func (ms *ManagerService) initDatastore(app string) error {
+	if initFn, ok := ms.backend(app); ok {
+		return ms.initBackend(app, initFn, false)
+	}
+
+	ms.dbmu.RLock()
+	dbc := ms.DBConfig[app]
+	ms.dbmu.RUnlock()
+
+	if dbc != nil && dbc.Connection != nil && dbc.Connection.DB().Ping() == nil {
+		return nil
+	}
 
-	// Init the DB, which pulls in our gorm DB struct;
-	ms.DBConfig[app].Init()
+	return ms.connectDatastore(app)
+}
 
-	return success
+// connectDatastore reads the datastore config for app, opens the new
+// connection, stores it and closes any it replaces, then runs Migrate when
+// automigrate is enabled.
+func (ms *ManagerService) connectDatastore(app string) error {
+	err := ms.openAppDatastore(app)
+	ms.emit(EventDatastoreConnected, app, err)
+	if err != nil {
+		return err
+	}
+	return ms.autoMigrate(app)
 }
 
-// InitManager reads in configuration data and prepares the datastore config.
-func (ms *ManagerService) InitManager(cfgfile string) {
-	if _, err := os.Stat(cfgfile); os.IsNotExist(err) {
-		log.Fatalf("File '%s' does not exist.\n", cfgfile)
+// openAppDatastore does the work of connectDatastore.
+func (ms *ManagerService) openAppDatastore(app string) error {
+	// pull in the database config to DBConfig struct
+	dbc, params, err := ms.resolveDatastore(app)
+	if err != nil {
+		return err
+	}
+
+	if err := prepareSQLitePath(dbc); err != nil {
+		return fmt.Errorf("[%s] %s", app, err)
+	}
+	ms.warnUnsupportedOptions(app)
+
+	params, err = ms.connParams(app, dbc, params)
+	if err != nil {
+		return fmt.Errorf("[%s] %s", app, err)
+	}
+
+	if logDSN, _ := ms.GetAppPropertyBool(app, "db_log_dsn"); logDSN {
+		ms.AppLog(app).Infof("InitDatastore: [%s] connecting to %s", app, redactedDSN(dbc, params))
+	}
+
+	session, err := ms.sessionOptions(app)
+	if err != nil {
+		return fmt.Errorf("[%s] %s", app, err)
+	}
+
+	// apps pointing at the same database share one pool, unless they
+	// disagree on connection-wide gorm behaviour
+	key := poolKey(dbc, params)
+	if key != "" && session.skipTransaction {
+		key += " skip_default_transaction"
+	}
+	// a pool no other app holds is reopened rather than rejoined; the
+	// previous connection is only released once the new one is in place
+	ms.dbmu.RLock()
+	previous, previousPool := ms.DBConfig[app], ms.appPools[app]
+	ms.dbmu.RUnlock()
+	pool := ms.acquirePool(key, previousPool)
+	if pool != nil {
+		ms.AppLog(app).Debugf("InitDatastore: [%s] shares an existing connection.", app)
+		dbc.Connection = pool.conn
 	} else {
-		ms.Config, _ = toml.LoadFile(cfgfile)
+		// Init the DB, which pulls in our gorm DB struct;
+		if err := openDatastore(dbc, params); err != nil {
+			return fmt.Errorf("[%s] %s", app, err)
+		}
+		if dbc.Connection == nil {
+			return fmt.Errorf("[%s] unable to connect to the datastore.", app)
+		}
+		if session.skipTransaction {
+			skipDefaultTransaction(dbc.Connection)
+		}
+		pool = ms.newPool(key, dbc.Connection)
+	}
+	if session.saveAssociations != nil {
+		dbc.Connection = dbc.Connection.Set("gorm:save_associations", *session.saveAssociations)
+	}
+
+	ms.dbmu.Lock()
+	if ms.DBConfig == nil {
 		ms.DBConfig = make(map[string]*superbase.DBConfig)
 	}
+	if ms.appPools == nil {
+		ms.appPools = make(map[string]*sharedPool)
+	}
+	ms.DBConfig[app] = dbc
+	ms.appPools[app] = pool
+	ms.dbmu.Unlock()
+
+	ms.releaseConnection(app, previous, previousPool)
+	return nil
+}
+
+// InitManager reads in configuration data and prepares the datastore config,
+// returning an error when the file is missing or invalid.  Files ending in
+// .yaml, .yml or .json are read in that format, anything else as TOML.  Use
+// InitManagerProvider for configuration from other sources.
+func (ms *ManagerService) InitManager(cfgfile string) error {
+	return ms.InitManagerProvider(NewFileProvider(cfgfile, ""))
+}
+
+// NewManagerFromEnv creates a manager from the configuration file named by
+// the GOVERNOR_CONFIG environment variable, defaulting to ./config.toml.
+func NewManagerFromEnv() (*ManagerService, error) {
+	ms := &ManagerService{}
+	if err := ms.InitManager(config.Getenv("GOVERNOR_CONFIG", "./config.toml")); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+
+// ResolveAddress returns the host:port app listens on, from the APPNAME_HOST
+// and APPNAME_PORT environment variables or the app's host and port
+// properties, defaulting to 127.0.0.1:7990.  IPv6 literals such as ::1 are
+// bracketed.  The port must be numeric and at most 65535; 0 picks an
+// ephemeral port.
+func (ms *ManagerService) ResolveAddress(app string) (string, error) {
+	host, port := "127.0.0.1", "7990"
+	if v, ok := ms.lookupAppProperty(app, "host"); ok {
+		host = fmt.Sprint(v)
+	}
+	if v, ok := ms.lookupAppProperty(app, "port"); ok {
+		port = strings.TrimSpace(fmt.Sprint(v))
+	}
+
+	if err := checkPort(app, "port", port); err != nil {
+		return "", err
+	}
+
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return net.JoinHostPort(host, port), nil
 }
 
+// checkPort returns an error naming where property came from, its
+// environment variable or the config file, unless port is a valid port.
+func checkPort(app string, property string, port string) error {
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		source := fmt.Sprintf("'%s' under [%s] heading", property, app)
+		if _, ok := os.LookupEnv(envKey(app, property)); ok {
+			source = envKey(app, property) + " environment variable"
+		}
+		return fmt.Errorf("Invalid %s '%s' from %s: must be a number from 1 to 65535, or 0 for an ephemeral port.", property, port, source)
+	}
+	return nil
+}
 
 // CreateAPI sets up the web service for app
 func (ms *ManagerService) CreateAPI(app string) *API {
-	// the env convention here is APPNAME_HOST and APPNAME_PORT
-	ua := strings.ToUpper(app)
-	address := config.Getenv(ua + "_HOST", "127.0.0.1") + ":" + config.Getenv(ua + "_PORT", "7990")
+	api, err := ms.createAPI(app)
+	if err != nil {
+		ms.fatalf("%s", err)
+	}
+	return api
+}
+
+// createAPI does the work of CreateAPI, returning an error for invalid
+// configuration.
+func (ms *ManagerService) createAPI(app string) (*API, error) {
+	address, err := ms.ResolveAddress(app)
+	if err != nil {
+		return nil, fmt.Errorf("CreateAPI: %s", err)
+	}
 	ws := fibre.NewWebService(app, address)
 	
 	// Create a new API bridge
@@ -87,11 +413,223 @@ func (ms *ManagerService) CreateAPI(app string) *API {
 		ws, // web service
 		ms,	// manager service
 	)
+	api.Address = address
+	api.app = app
+	ms.configureBasePath(api, app)
+	api.auth = ms.adminAuth(app)
 
-	return api
+	if err := ms.configureTLS(api, app); err != nil {
+		return nil, fmt.Errorf("CreateAPI: %s", err)
+	}
+	if err := ms.configureAutocert(api, app); err != nil {
+		return nil, fmt.Errorf("CreateAPI: %s", err)
+	}
+	if err := ms.configureExempt(api, app); err != nil {
+		return nil, fmt.Errorf("CreateAPI: %s", err)
+	}
+	if err := ms.configureAccessLog(api, app); err != nil {
+		return nil, fmt.Errorf("CreateAPI: %s", err)
+	}
+	if err := ms.configureMetrics(api, app); err != nil {
+		return nil, fmt.Errorf("CreateAPI: %s", err)
+	}
+	if err := ms.configureCORS(api, app); err != nil {
+		return nil, fmt.Errorf("CreateAPI: %s", err)
+	}
+	if err := ms.configureBodyLimit(api, app); err != nil {
+		return nil, fmt.Errorf("CreateAPI: %s", err)
+	}
+	if err := ms.configureSlowRequests(api, app); err != nil {
+		return nil, fmt.Errorf("CreateAPI: %s", err)
+	}
+	if err := ms.configureRequestTimeout(api, app); err != nil {
+		return nil, fmt.Errorf("CreateAPI: %s", err)
+	}
+	if err := ms.configureDrain(api, app); err != nil {
+		return nil, fmt.Errorf("CreateAPI: %s", err)
+	}
+	if err := ms.configureShutdown(api, app); err != nil {
+		return nil, fmt.Errorf("CreateAPI: %s", err)
+	}
+	if err := ms.configureHealth(api, app); err != nil {
+		return nil, fmt.Errorf("CreateAPI: %s", err)
+	}
+	ms.configureAdminConfig(api, app)
+	ms.configureTasks(api, app)
+	api.gracefulRestart, _ = ms.GetAppPropertyBool(app, "graceful_restart")
+
+	return api, nil
 }
 
-// Daemonize the API.
+// Daemonize the API, blocking until the server fails or the process receives
+// SIGINT or SIGTERM, at which point the manager shuts down gracefully.  With
+// graceful_restart enabled, SIGUSR2 starts a new process which inherits the
+// listening socket before this one drains and exits.
 func (ms *ManagerService) Daemonize(api *API) {
-	api.WebService.RunWebServer()
+	if err := ms.daemonize(api); err != nil {
+		ms.fatalf("%s", err)
+	}
+}
+
+// daemonize runs apis until one fails or the process receives SIGINT or
+// SIGTERM.  After SIGINT, usually Ctrl-C during development, in-flight
+// requests get the shorter interrupt_timeout.
+func (ms *ManagerService) daemonize(apis ...*API) error {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	var interrupted int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case s := <-sig:
+			if s == syscall.SIGINT {
+				atomic.StoreInt32(&interrupted, 1)
+			}
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ms.run(ctx, apis, func() bool { return atomic.LoadInt32(&interrupted) == 1 })
+}
+
+// Serve runs the API until ctx is done or the server fails, then shuts it
+// down gracefully, along with the manager once no other API is serving.  It
+// returns the server's error, or nil after a clean shutdown.  Unlike
+// Daemonize it installs no signal handlers, which suits running a server
+// from tests or inside another program.  In-flight requests get the app's
+// shutdown_timeout to finish.
+func (ms *ManagerService) Serve(ctx context.Context, api *API) error {
+	return ms.run(ctx, []*API{api}, nil)
+}
+
+// run starts the scheduled tasks and serves apis concurrently until ctx is
+// done or one of them fails, which stops the rest, then completes the
+// manager's shutdown unless another run is still serving.  It returns
+// the first server error.  interrupted, when set, reports whether the
+// shorter interrupt_timeout applies to draining.
+func (ms *ManagerService) run(ctx context.Context, apis []*API, interrupted func() bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ms.beginRun()
+	ms.startTasks()
+
+	// the manager starts shutting down as soon as this run does
+	var stopping sync.Once
+	beginShutdown := func() { stopping.Do(ms.beginShutdown) }
+	go func() {
+		<-ctx.Done()
+		beginShutdown()
+	}()
+
+	// a graceful restart hands every listener to one new process, then
+	// drains them all here
+	restart, stopRestart := ms.restartSignal(apis)
+	defer stopRestart()
+	go func() {
+		for {
+			select {
+			case <-restart:
+				if err := handoff(apis...); err != nil {
+					ms.Log().Errorf("Serve: graceful restart failed: %s", err)
+					continue
+				}
+				cancel()
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	errs := make(chan error, len(apis))
+	for _, api := range apis {
+		go func(api *API) {
+			timeout := func() time.Duration {
+				if interrupted != nil && interrupted() {
+					return api.interruptTimeout
+				}
+				return api.shutdownTimeout
+			}
+			err := ms.serve(ctx, api, timeout)
+			if err != nil {
+				cancel()
+				if len(apis) > 1 {
+					err = fmt.Errorf("[%s] %s", api.app, err)
+				}
+			}
+			errs <- err
+		}(api)
+	}
+
+	var first error
+	for range apis {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+
+	beginShutdown()
+	ms.finishShutdown()
+	for _, api := range apis {
+		ms.emit(EventShutdownComplete, api.app, nil)
+	}
+	return first
+}
+
+// serve runs a single API until ctx is done, it fails or it is drained,
+// then stops its servers, bounding the drain by the duration timeout
+// returns.
+func (ms *ManagerService) serve(ctx context.Context, api *API, timeout func() time.Duration) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	api.lifeMu.Lock()
+	api.ctx, api.cancel = ctx, cancel
+	api.lifeMu.Unlock()
+
+	if api.healthInterval > 0 {
+		watched := make(chan struct{})
+		go func() {
+			defer close(watched)
+			api.watchHealth(ctx)
+		}()
+		// the watcher stops with the API, before serve returns
+		defer func() {
+			cancel()
+			<-watched
+		}()
+	}
+
+	srv := api.newServer()
+	servers := []*http.Server{srv}
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- api.listen(srv)
+	}()
+
+	if redirect := api.newRedirectServer(); redirect != nil {
+		servers = append(servers, redirect)
+		go func() {
+			errs <- api.listenRedirect(redirect)
+		}()
+	}
+
+	var err error
+	select {
+	case err = <-errs:
+		if err == http.ErrServerClosed {
+			err = nil
+		}
+	case <-ctx.Done():
+	case <-api.drained:
+	}
+
+	ms.emit(EventShutdownInitiated, api.app, nil)
+	api.stopServers(timeout(), servers...)
+	return err
 }
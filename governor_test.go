@@ -0,0 +1,67 @@
+package governor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveAddress(t *testing.T) {
+	cases := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{"defaults", "[testapp]\n", "127.0.0.1:7990"},
+		{"ipv4", "[testapp]\nhost = \"127.0.0.1\"\nport = 0\n", "127.0.0.1:0"},
+		{"ipv6", "[testapp]\nhost = \"::1\"\nport = 8080\n", "[::1]:8080"},
+		{"ipv6 brackets", "[testapp]\nhost = \"[::1]\"\nport = \"8080\"\n", "[::1]:8080"},
+		{"all interfaces", "[testapp]\nhost = \"0.0.0.0\"\nport = 65535\n", "0.0.0.0:65535"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := newTestManager(t, c.config).ResolveAddress("testapp")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestResolveAddressBadPort(t *testing.T) {
+	cases := []struct {
+		name   string
+		toml   string
+		env    string
+		source string
+	}{
+		{name: "toml negative", toml: "-1", source: "'port' under [testapp] heading"},
+		{name: "toml too large", toml: "65536", source: "'port' under [testapp] heading"},
+		{name: "toml not a number", toml: `"http"`, source: "'port' under [testapp] heading"},
+		{name: "env negative", env: "-1", source: "TESTAPP_PORT environment variable"},
+		{name: "env too large", env: "65536", source: "TESTAPP_PORT environment variable"},
+		{name: "env not a number", env: "http", source: "TESTAPP_PORT environment variable"},
+		{name: "env over good toml", toml: "8080", env: "http", source: "TESTAPP_PORT environment variable"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := "[testapp]\n"
+			if c.toml != "" {
+				config += "port = " + c.toml + "\n"
+			}
+			if c.env != "" {
+				t.Setenv("TESTAPP_PORT", c.env)
+			}
+
+			got, err := newTestManager(t, config).ResolveAddress("testapp")
+			if err == nil {
+				t.Fatalf("got %q, want an error", got)
+			}
+			if !strings.Contains(err.Error(), c.source) {
+				t.Errorf("error %q does not name %s", err, c.source)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package governor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lakesite/ls-superbase"
+)
+
+// readinessTimeout bounds the checks run by the readiness endpoint unless
+// health_timeout is set.
+const readinessTimeout = 5 * time.Second
+
+// namedCheck is a health check registered with AddHealthCheck.
+type namedCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// healthReport is the JSON body written by the readiness endpoint.
+type healthReport struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// AddHealthCheck registers a check the readiness endpoint of app runs
+// alongside its datastore ping, reported in the response under name.
+func (ms *ManagerService) AddHealthCheck(app string, name string, check func(ctx context.Context) error) {
+	ms.healthMu.Lock()
+	defer ms.healthMu.Unlock()
+
+	if ms.healthChecks == nil {
+		ms.healthChecks = make(map[string][]namedCheck)
+	}
+	ms.healthChecks[app] = append(ms.healthChecks[app], namedCheck{name: name, check: check})
+}
+
+// readinessChecks returns the checks for app: a "datastore" ping when it has
+// an initialized datastore, followed by those added with AddHealthCheck.
+func (ms *ManagerService) readinessChecks(app string) []namedCheck {
+	checks := []namedCheck{}
+	if ping, ok := ms.datastoreChecks()[app]; ok {
+		checks = append(checks, namedCheck{name: "datastore", check: ping})
+	}
+
+	ms.healthMu.RLock()
+	checks = append(checks, ms.healthChecks[app]...)
+	ms.healthMu.RUnlock()
+
+	return checks
+}
+
+// runChecks runs checks concurrently, returning each result by name.
+func runChecks(ctx context.Context, checks []namedCheck) map[string]error {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]error, len(checks))
+
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c namedCheck) {
+			defer wg.Done()
+			err := c.check(ctx)
+			mu.Lock()
+			results[c.name] = err
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Defaults for the health endpoints, see configureHealth.
+const (
+	defaultHealthPath = "/healthz"
+	defaultReadyPath  = "/readyz"
+)
+
+// readiness runs the API's readiness checks, returning the report and the
+// status code it should be served with.
+func (api *API) readiness(ctx context.Context) (healthReport, int) {
+	ctx, cancel := context.WithTimeout(ctx, api.healthTimeout)
+	defer cancel()
+
+	report := healthReport{Status: "ready"}
+	status := http.StatusOK
+
+	results := runChecks(ctx, api.ManagerService.readinessChecks(api.app))
+	report.Checks = make(map[string]string, len(results))
+	for name, err := range results {
+		if err != nil {
+			report.Checks[name] = err.Error()
+			report.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+		} else {
+			report.Checks[name] = "ok"
+		}
+	}
+	return report, status
+}
+
+// readyHandler reports whether the API should receive traffic, failing with
+// 503 while draining or when any readiness check fails.  With a
+// health_interval the latest background result is served instead of running
+// the checks per request.
+func (api *API) readyHandler(w http.ResponseWriter, r *http.Request) {
+	var report healthReport
+	var status int
+
+	api.healthMu.RLock()
+	cached, cachedStatus := api.lastReport, api.lastStatus
+	api.healthMu.RUnlock()
+
+	switch {
+	case api.Draining():
+		report, status = healthReport{Status: "draining"}, http.StatusServiceUnavailable
+	case cached != nil:
+		report, status = *cached, cachedStatus
+	default:
+		report, status = api.readiness(r.Context())
+	}
+
+	writeHealth(w, report, status)
+}
+
+// liveHandler reports that the process is up and serving, without running
+// any checks, so a failing datastore does not get the process restarted.
+func (api *API) liveHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, healthReport{Status: "ok"}, http.StatusOK)
+}
+
+func writeHealth(w http.ResponseWriter, report healthReport, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// watchHealth runs the readiness checks every health_interval until ctx is
+// done, caching the result for readyHandler.
+func (api *API) watchHealth(ctx context.Context) {
+	ticker := time.NewTicker(api.healthInterval)
+	defer ticker.Stop()
+
+	for {
+		report, status := api.readiness(ctx)
+		api.healthMu.Lock()
+		api.lastReport, api.lastStatus = &report, status
+		api.healthMu.Unlock()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// configureHealth mounts the liveness and readiness endpoints on the API,
+// at health_path and ready_path, with their checks bounded by
+// health_timeout.  With health_interval set the readiness checks run in the
+// background at that interval while the API is served, rather than on every
+// request.
+func (ms *ManagerService) configureHealth(api *API, app string) error {
+	api.healthTimeout = readinessTimeout
+	if _, ok := ms.lookupAppProperty(app, "health_timeout"); ok {
+		d, err := ms.GetAppPropertyDuration(app, "health_timeout")
+		if err != nil {
+			return err
+		}
+		api.healthTimeout = d
+	}
+
+	if _, ok := ms.lookupAppProperty(app, "health_interval"); ok {
+		interval, err := ms.GetAppPropertyDuration(app, "health_interval")
+		if err != nil {
+			return err
+		}
+		if interval <= 0 {
+			return fmt.Errorf("Configuration 'health_interval' under [%s] heading must be positive.", app)
+		}
+		api.healthInterval = interval
+	}
+
+	healthPath, readyPath := defaultHealthPath, defaultReadyPath
+	if p, err := ms.GetAppProperty(app, "health_path"); err == nil {
+		healthPath = "/" + strings.TrimPrefix(p, "/")
+	}
+	if p, err := ms.GetAppProperty(app, "ready_path"); err == nil {
+		readyPath = "/" + strings.TrimPrefix(p, "/")
+	}
+
+	api.GET(healthPath, api.liveHandler)
+	api.GET(readyPath, api.readyHandler)
+	return nil
+}
+
+// HealthAll pings every initialized datastore concurrently, bounded by ctx,
+// and returns the result for each app; a nil error means the app is healthy.
+func (ms *ManagerService) HealthAll(ctx context.Context) map[string]error {
+	checks := []namedCheck{}
+	for app, ping := range ms.datastoreChecks() {
+		checks = append(checks, namedCheck{name: app, check: ping})
+	}
+	return runChecks(ctx, checks)
+}
+
+// datastoreChecks returns a ping for each app's gorm connection or backend
+// store.
+func (ms *ManagerService) datastoreChecks() map[string]func(context.Context) error {
+	ms.dbmu.RLock()
+	defer ms.dbmu.RUnlock()
+
+	checks := make(map[string]func(context.Context) error, len(ms.DBConfig)+len(ms.stores))
+	for app, dbc := range ms.DBConfig {
+		dbc := dbc
+		checks[app] = func(ctx context.Context) error { return pingDatastore(ctx, dbc) }
+	}
+	for app, store := range ms.stores {
+		store := store
+		checks[app] = func(ctx context.Context) error { return pingStore(ctx, store) }
+	}
+	return checks
+}
+
+// errNotConnected is reported for datastores without a live connection.
+var errNotConnected = errors.New("Datastore is not connected.")
+
+// pingDatastore checks that dbc has a live connection.
+func pingDatastore(ctx context.Context, dbc *superbase.DBConfig) error {
+	if dbc == nil || dbc.Connection == nil {
+		return errNotConnected
+	}
+	return dbc.Connection.DB().PingContext(ctx)
+}
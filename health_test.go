@@ -0,0 +1,149 @@
+package governor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHealthWatcherRunsWhileServed(t *testing.T) {
+	ms := newTestManager(t, "[testapp]\nport = 0\nhealth_interval = \"10ms\"\n")
+	checks := make(chan struct{}, 100)
+	ms.AddHealthCheck("testapp", "probe", func(ctx context.Context) error {
+		checks <- struct{}{}
+		return nil
+	})
+
+	api, err := ms.createAPI("testapp")
+	if err != nil {
+		t.Fatalf("createAPI: %s", err)
+	}
+	select {
+	case <-checks:
+		t.Fatal("health checks ran before the API was served")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := api.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+	select {
+	case <-checks:
+	case <-time.After(5 * time.Second):
+		t.Fatal("health checks did not run while serving")
+	}
+
+	rec := httptest.NewRecorder()
+	api.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, defaultReadyPath, nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("readiness got %d, want 200", rec.Code)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := api.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %s", err)
+	}
+
+	// drain any check already in flight, then expect no more
+	for len(checks) > 0 {
+		<-checks
+	}
+	select {
+	case <-checks:
+		t.Error("health checks still running after shutdown")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// testStore is a backend store whose Ping returns err, or with block set
+// waits for its context.
+type testStore struct {
+	err   error
+	block bool
+}
+
+func (s *testStore) Ping(ctx context.Context) error {
+	if s.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return s.err
+}
+
+func (s *testStore) Close() error { return nil }
+
+func TestHealthAll(t *testing.T) {
+	dir := t.TempDir()
+	ms := newTestManager(t, fmt.Sprintf(`[db]
+dbdriver = "sqlite3"
+dbpath = %q
+
+[closed]
+dbdriver = "sqlite3"
+dbpath = %q
+
+[down]
+dbdriver = "test"
+
+[slow1]
+dbdriver = "test"
+block = true
+
+[slow2]
+dbdriver = "test"
+block = true
+
+[nodb]
+port = 0
+`, filepath.Join(dir, "db.db"), filepath.Join(dir, "closed.db")))
+	t.Cleanup(func() { ms.Close() })
+
+	errDown := errors.New("connection refused")
+	ms.RegisterDatastore("test", func(cfg map[string]interface{}) (io.Closer, error) {
+		if cfg["block"] == true {
+			return &testStore{block: true}, nil
+		}
+		return &testStore{err: errDown}, nil
+	})
+	if err := ms.InitAllDatastores(true); err != nil {
+		t.Fatalf("InitAllDatastores: %s", err)
+	}
+	ms.DBConfig["closed"].Connection.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results := ms.HealthAll(ctx)
+	// the slow checks share the deadline rather than running one after another
+	if elapsed := time.Since(start); elapsed > 350*time.Millisecond {
+		t.Errorf("HealthAll took %s, want the checks run concurrently", elapsed)
+	}
+
+	if len(results) != 5 {
+		t.Errorf("results = %v, want one per initialized datastore", results)
+	}
+	if err, ok := results["db"]; !ok || err != nil {
+		t.Errorf("db = %v, want healthy", err)
+	}
+	if results["closed"] == nil {
+		t.Error("closed connection reported healthy")
+	}
+	if results["down"] != errDown {
+		t.Errorf("down = %v, want %v", results["down"], errDown)
+	}
+	for _, app := range []string{"slow1", "slow2"} {
+		if !errors.Is(results[app], context.DeadlineExceeded) {
+			t.Errorf("%s = %v, want the deadline", app, results[app])
+		}
+	}
+	if _, ok := results["nodb"]; ok {
+		t.Error("an app without a datastore was checked")
+	}
+}
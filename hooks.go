@@ -0,0 +1,93 @@
+package governor
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/lakesite/ls-fibre"
+)
+
+// AccessLogMiddleware is the name of the middleware installed by access_log,
+// and RecoverMiddleware the name to Use Recover under, for use with Exempt.
+const (
+	AccessLogMiddleware = "access_log"
+	RecoverMiddleware   = "recover"
+)
+
+// Register calls fn with the API's web service and manager, so an app can
+// wire its routes, with access to its datastore and config, in one place:
+//
+//	gapi.Register(func(ws *fibre.WebService, ms *governor.ManagerService) {
+//		db, _ := ms.DB("example_app")
+//		ws.Router.HandleFunc("/items", listItems(db))
+//	})
+//
+// Routes added to ws.Router directly still pass through the API's
+// middleware but are not listed by Routes.
+func (api *API) Register(fn func(ws *fibre.WebService, ms *ManagerService)) {
+	fn(api.WebService, api.ManagerService)
+}
+
+// Chain composes middleware into one, the first listed running first.
+func Chain(mw ...Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		for i := len(mw) - 1; i >= 0; i-- {
+			next = mw[i](next)
+		}
+		return next
+	}
+}
+
+// configureAccessLog installs the access log middleware when access_log is
+// enabled for app.
+func (ms *ManagerService) configureAccessLog(api *API, app string) error {
+	if _, ok := ms.lookupAppProperty(app, "access_log"); !ok {
+		return nil
+	}
+
+	enabled, err := ms.GetAppPropertyBool(app, "access_log")
+	if err != nil {
+		return err
+	}
+	if enabled {
+		api.Use(AccessLogMiddleware, api.AccessLog())
+	}
+	return nil
+}
+
+// AccessLog returns middleware which logs each request's method, path,
+// status and duration at info level.
+func (api *API) AccessLog() Middleware {
+	ms := api.ManagerService
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := ms.now()
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+			ms.AppLog(api.app).Infof("%s %s %d %s", r.Method, r.URL.Path, sw.Status(), ms.now().Sub(start))
+		})
+	}
+}
+
+// Recover returns middleware which turns a panicking handler into a 500
+// Internal Server Error JSON response, logging the panic and stack trace.
+// http.ErrAbortHandler is re-raised so net/http can abort the response as
+// intended.
+func (api *API) Recover() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				api.ManagerService.AppLog(api.app).Errorf("Recover: %s %s panicked: %v\n%s", r.Method, r.URL.Path, p, debug.Stack())
+				api.WebService.JsonStatusResponse(w, "Internal server error.", http.StatusInternalServerError)
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
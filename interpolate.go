@@ -0,0 +1,177 @@
+package governor
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Resolver resolves the reference in a ${scheme:reference} config value,
+// such as the APP_DB_PASSWORD of "${env:APP_DB_PASSWORD}".  Values are
+// resolved each time they are looked up, so resolvers backed by a remote
+// secret store should cache.
+type Resolver interface {
+	Resolve(ref string) (string, error)
+}
+
+// ResolverFunc adapts a function to the Resolver interface.
+type ResolverFunc func(ref string) (string, error)
+
+// Resolve calls f(ref).
+func (f ResolverFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+// interpolation matches a ${scheme:reference} token in a config value.
+// Plain ${VAR} tokens have no scheme and are left alone.
+var interpolation = regexp.MustCompile(`\$\{([a-zA-Z][a-zA-Z0-9_]*):([^}]*)\}`)
+
+// RegisterResolver registers r for ${scheme:...} tokens in config values,
+// replacing any earlier resolver for scheme.  The env scheme, which reads
+// an environment variable, and the file scheme, which reads a file such as
+// a mounted secret and trims surrounding whitespace, are built in and may
+// be replaced too.
+func (ms *ManagerService) RegisterResolver(scheme string, r Resolver) {
+	if ms.parent != nil {
+		ms.parent.RegisterResolver(scheme, r)
+		return
+	}
+
+	ms.resolversMu.Lock()
+	defer ms.resolversMu.Unlock()
+
+	if ms.resolvers == nil {
+		ms.resolvers = make(map[string]Resolver)
+	}
+	ms.resolvers[scheme] = r
+}
+
+// resolver returns the resolver for scheme, falling back to the built-ins.
+func (ms *ManagerService) resolver(scheme string) (Resolver, bool) {
+	if ms.parent != nil {
+		return ms.parent.resolver(scheme)
+	}
+
+	ms.resolversMu.RLock()
+	r, ok := ms.resolvers[scheme]
+	ms.resolversMu.RUnlock()
+	if ok {
+		return r, true
+	}
+
+	switch scheme {
+	case "env":
+		return ResolverFunc(resolveEnv), true
+	case "file":
+		return ResolverFunc(func(path string) (string, error) {
+			data, err := readFile(path, ms.fileTimeout())
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSpace(string(data)), nil
+		}), true
+	}
+	return nil, false
+}
+
+func resolveEnv(name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("Environment variable '%s' is not set.", name)
+	}
+	return v, nil
+}
+
+// interpolate replaces every ${scheme:reference} token in s with its
+// resolved value.  Tokens which fail to resolve are left in place, so
+// CheckPlaceholders still reports them, and the first failure is returned.
+func (ms *ManagerService) interpolate(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var first error
+	out := interpolation.ReplaceAllStringFunc(s, func(token string) string {
+		m := interpolation.FindStringSubmatch(token)
+		r, ok := ms.resolver(m[1])
+		if !ok {
+			if first == nil {
+				first = fmt.Errorf("No resolver is registered for %s.", token)
+			}
+			return token
+		}
+		v, err := r.Resolve(m[2])
+		if err != nil {
+			if first == nil {
+				first = fmt.Errorf("Unable to resolve %s: %s", token, err)
+			}
+			return token
+		}
+		return v
+	})
+	return out, first
+}
+
+// interpolateValue interpolates v if it is a string, or the strings within
+// it if it is a table or array, as walkValue visits them.  Arrays are
+// copied, since they may belong to the configuration tree; tables are
+// interpolated in place.  The dotted key of the first failure is returned
+// with it, relative to v.
+func (ms *ManagerService) interpolateValue(v interface{}) (interface{}, string, error) {
+	switch t := v.(type) {
+	case string:
+		s, err := ms.interpolate(t)
+		return s, "", err
+	case map[string]interface{}:
+		key, err := ms.interpolateMap(t)
+		return t, key, err
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		var failed string
+		var first error
+		for i, e := range t {
+			resolved, key, err := ms.interpolateValue(e)
+			out[i] = resolved
+			if err != nil && first == nil {
+				failed, first = joinKey(fmt.Sprintf("[%d]", i), key), err
+			}
+		}
+		return out, failed, first
+	case []map[string]interface{}:
+		var failed string
+		var first error
+		for i, e := range t {
+			if key, err := ms.interpolateMap(e); err != nil && first == nil {
+				failed, first = joinKey(fmt.Sprintf("[%d]", i), key), err
+			}
+		}
+		return t, failed, first
+	}
+	return v, "", nil
+}
+
+// interpolateMap interpolates the values of m in place, returning the
+// dotted key of a failure along with it.
+func (ms *ManagerService) interpolateMap(m map[string]interface{}) (string, error) {
+	var failed string
+	var first error
+	for k, v := range m {
+		resolved, key, err := ms.interpolateValue(v)
+		m[k] = resolved
+		if err != nil && first == nil {
+			failed, first = joinKey(k, key), err
+		}
+	}
+	return failed, first
+}
+
+func joinKey(key string, sub string) string {
+	switch {
+	case sub == "":
+		return key
+	case strings.HasPrefix(sub, "["):
+		return key + sub
+	}
+	return key + "." + sub
+}
@@ -0,0 +1,156 @@
+package governor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInterpolateBuiltins(t *testing.T) {
+	t.Setenv("INTERPOLATE_USER", "env-user")
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("  file-password\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ms := newTestManager(t, fmt.Sprintf(`[testapp]
+dbuser = "${env:INTERPOLATE_USER}"
+dbpassword = "${file:%s}"
+dsn = "user=${env:INTERPOLATE_USER} password=${file:%s}"
+plain = "${NOT_A_SCHEME}"
+`, path, path))
+
+	for property, want := range map[string]string{
+		"dbuser":     "env-user",
+		"dbpassword": "file-password",
+		"dsn":        "user=env-user password=file-password",
+		"plain":      "${NOT_A_SCHEME}",
+	} {
+		got, err := ms.GetAppProperty("testapp", property)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", property, err)
+		} else if got != want {
+			t.Errorf("%s = %q, want %q", property, got, want)
+		}
+	}
+}
+
+func TestInterpolateFailures(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	cases := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{"unknown scheme", "${vault:db/password}", "No resolver is registered for ${vault:db/password}."},
+		{"missing file", "${file:" + missing + "}", "Unable to resolve ${file:" + missing + "}"},
+		{"unset variable", "${env:INTERPOLATE_UNSET}", "Environment variable 'INTERPOLATE_UNSET' is not set."},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ms := newTestManager(t, fmt.Sprintf("[testapp]\nsecret = %q\n", c.value))
+
+			_, err := ms.GetAppProperty("testapp", "secret")
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) || !strings.Contains(err.Error(), "'secret' under [testapp] heading") {
+				t.Errorf("got error %v, want one naming secret and containing %q", err, c.wantErr)
+			}
+
+			// the token is left in place for CheckPlaceholders to report
+			var report *ValidationError
+			if err := ms.CheckPlaceholders(true); !errors.As(err, &report) || len(report.Problems) != 1 || !strings.Contains(report.Problems[0].Problem, c.value) {
+				t.Errorf("CheckPlaceholders = %v, want the unresolved %s", err, c.value)
+			}
+		})
+	}
+}
+
+func TestRegisterResolver(t *testing.T) {
+	ms := newTestManager(t, "[testapp]\nsecret = \"${vault:db/password}\"\nuser = \"${env:INTERPOLATE_USER}\"\n")
+	if err := ms.CheckPlaceholders(true); err == nil {
+		t.Fatal("CheckPlaceholders passed before the resolver was registered")
+	}
+
+	refs := []string{}
+	ms.RegisterResolver("vault", ResolverFunc(func(ref string) (string, error) {
+		refs = append(refs, ref)
+		return "from-vault", nil
+	}))
+	// built-in schemes can be replaced too
+	ms.RegisterResolver("env", ResolverFunc(func(ref string) (string, error) {
+		return "replaced-" + ref, nil
+	}))
+
+	if got, err := ms.GetAppProperty("testapp", "secret"); err != nil || got != "from-vault" {
+		t.Errorf("secret = %q, %v, want from-vault", got, err)
+	}
+	if len(refs) != 1 || refs[0] != "db/password" {
+		t.Errorf("resolver called with %v, want [db/password]", refs)
+	}
+	if got, err := ms.GetAppProperty("testapp", "user"); err != nil || got != "replaced-INTERPOLATE_USER" {
+		t.Errorf("user = %q, %v, want the replaced env resolver's value", got, err)
+	}
+	if err := ms.CheckPlaceholders(true); err != nil {
+		t.Errorf("CheckPlaceholders: %s", err)
+	}
+
+	// a WithEnvironment view shares its parent's resolvers
+	if got, err := ms.WithEnvironment("staging").GetAppProperty("testapp", "secret"); err != nil || got != "from-vault" {
+		t.Errorf("view secret = %q, %v, want from-vault", got, err)
+	}
+}
+
+func TestInterpolateNested(t *testing.T) {
+	t.Setenv("INTERPOLATE_VALUE", "resolved")
+	ms := newTestManager(t, `[testapp]
+list = ["${env:INTERPOLATE_VALUE}", "plain"]
+mixed = ["${env:INTERPOLATE_VALUE}", { key = "${env:INTERPOLATE_VALUE}" }]
+
+[testapp.nested]
+key = "${env:INTERPOLATE_VALUE}"
+
+[[testapp.replicas]]
+host = "${env:INTERPOLATE_VALUE}"
+`)
+
+	list, err := ms.GetAppPropertyStrings("testapp", "list")
+	if err != nil || !reflect.DeepEqual(list, []string{"resolved", "plain"}) {
+		t.Errorf("list = %v, %v", list, err)
+	}
+
+	section, err := ms.GetAppSection("testapp")
+	if err != nil {
+		t.Fatalf("GetAppSection: %s", err)
+	}
+	if got := section["nested"].(map[string]interface{})["key"]; got != "resolved" {
+		t.Errorf("nested.key = %v", got)
+	}
+	if got := section["replicas"].([]interface{})[0].(map[string]interface{})["host"]; got != "resolved" {
+		t.Errorf("replicas[0].host = %v", got)
+	}
+	mixed := section["mixed"].([]interface{})
+	if mixed[0] != "resolved" || mixed[1].(map[string]interface{})["key"] != "resolved" {
+		t.Errorf("mixed = %v", mixed)
+	}
+
+	// the tree itself keeps the tokens
+	if raw, _ := ms.Get("testapp.list"); !reflect.DeepEqual(raw, []interface{}{"${env:INTERPOLATE_VALUE}", "plain"}) {
+		t.Errorf("tree list = %v, want the tokens", raw)
+	}
+}
+
+func TestInterpolateNestedFailureKey(t *testing.T) {
+	ms := newTestManager(t, `[testapp]
+[[testapp.replicas]]
+host = "one"
+
+[[testapp.replicas]]
+host = "${nope:x}"
+`)
+	_, err := ms.GetAppSection("testapp")
+	if err == nil || !strings.Contains(err.Error(), "'replicas[1].host' under [testapp] heading") {
+		t.Errorf("got error %v, want one naming replicas[1].host", err)
+	}
+}
@@ -0,0 +1,293 @@
+package governor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The defaults for how long in-flight requests may take to finish once
+// shutdown begins: after SIGTERM or Serve's context ends, and after SIGINT.
+const (
+	defaultShutdownTimeout  = 15 * time.Second
+	defaultInterruptTimeout = 2 * time.Second
+)
+
+// context returns the manager's lifecycle context, which is cancelled when
+// the last running server begins shutting down.
+func (ms *ManagerService) context() context.Context {
+	ms.lifeMu.Lock()
+	defer ms.lifeMu.Unlock()
+
+	if ms.ctx == nil {
+		ms.ctx, ms.cancel = context.WithCancel(context.Background())
+	}
+	return ms.ctx
+}
+
+// Done returns a channel which is closed when the manager begins shutting
+// down, e.g. after Daemonize receives SIGINT or SIGTERM or once Serve's
+// context ends, while in-flight requests are still draining.  Goroutines
+// started outside the web server can select on it to stop cleanly.
+func (ms *ManagerService) Done() <-chan struct{} {
+	return ms.context().Done()
+}
+
+// beginRun records a running Daemonize or Serve call, starting a fresh
+// lifecycle context when an earlier shutdown cancelled the last one.
+func (ms *ManagerService) beginRun() {
+	ms.lifeMu.Lock()
+	defer ms.lifeMu.Unlock()
+
+	if ms.ctx == nil || ms.ctx.Err() != nil {
+		ms.ctx, ms.cancel = context.WithCancel(context.Background())
+	}
+	ms.running++
+	ms.active++
+}
+
+// beginShutdown records that a run has started shutting down.  When no
+// other run is still serving, the manager's context is cancelled, which
+// closes Done and stops workers and tasks while the servers drain.
+func (ms *ManagerService) beginShutdown() {
+	ms.lifeMu.Lock()
+	defer ms.lifeMu.Unlock()
+
+	ms.active--
+	if ms.active == 0 {
+		ms.cancel()
+	}
+}
+
+// context returns the API's context, which is cancelled when its shutdown
+// begins, or the manager's while the API is not being served.
+func (api *API) context() context.Context {
+	api.lifeMu.Lock()
+	ctx := api.ctx
+	api.lifeMu.Unlock()
+
+	if ctx == nil {
+		return api.ManagerService.context()
+	}
+	return ctx
+}
+
+// stopServers cancels the API's context and drains in-flight requests on
+// each server for up to timeout.  The manager keeps running for any other
+// API it serves.
+func (api *API) stopServers(timeout time.Duration, servers ...*http.Server) {
+	api.lifeMu.Lock()
+	if api.cancel != nil {
+		api.cancel()
+	}
+	api.lifeMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, srv := range servers {
+		wg.Add(1)
+		go func(srv *http.Server) {
+			defer wg.Done()
+			if err := srv.Shutdown(ctx); err != nil {
+				api.ManagerService.AppLog(api.app).Errorf("Shutdown: %s", err)
+			}
+		}(srv)
+	}
+	wg.Wait()
+}
+
+// finishShutdown ends a run once its servers have stopped.  When no other
+// run is serving, the manager's workers and tasks are waited for and its
+// datastores closed.
+func (ms *ManagerService) finishShutdown() {
+	ms.lifeMu.Lock()
+	ms.running--
+	last := ms.running == 0
+	ms.lifeMu.Unlock()
+
+	if !last {
+		return
+	}
+	ms.workers.Wait()
+
+	// a later run starts the tasks again under its own context
+	ms.tasksMu.Lock()
+	ms.tasksStarted = false
+	ms.tasksMu.Unlock()
+
+	if err := ms.Close(); err != nil {
+		ms.Log().Errorf("Shutdown: %s", err)
+	}
+}
+
+// configureShutdown reads shutdown_timeout, how long in-flight requests may
+// take to finish after SIGTERM, and the usually shorter interrupt_timeout
+// applied after SIGINT, for app.
+func (ms *ManagerService) configureShutdown(api *API, app string) error {
+	for property, timeout := range map[string]*time.Duration{
+		"shutdown_timeout":  &api.shutdownTimeout,
+		"interrupt_timeout": &api.interruptTimeout,
+	} {
+		if _, ok := ms.lookupAppProperty(app, property); !ok {
+			continue
+		}
+		d, err := ms.GetAppPropertyDuration(app, property)
+		if err != nil {
+			return err
+		}
+		*timeout = d
+	}
+	return nil
+}
+
+// newServer creates the http.Server for the API and runs the OnServer hooks
+// against it.
+func (api *API) newServer() *http.Server {
+	srv := &http.Server{
+		Addr:      api.Address,
+		Handler:   api.mountBasePath(api.handler()),
+		TLSConfig: api.TLSConfig,
+	}
+	for _, fn := range api.onServer {
+		fn(srv)
+	}
+	return srv
+}
+
+// OnServer registers fn to adjust the http.Server Daemonize creates, for
+// settings governor has no config key for such as MaxHeaderBytes or
+// ConnState.  Hooks run in the order registered, after governor has applied
+// its own settings and before the listener is bound.
+func (api *API) OnServer(fn func(*http.Server)) {
+	api.onServer = append(api.onServer, fn)
+}
+
+// listen serves srv, using TLS when the API has a TLS configuration.  The
+// listener is inherited from the parent process under the API's app name
+// after a graceful restart.
+func (api *API) listen(srv *http.Server) error {
+	l, err := inheritedListener(api.app)
+	if err == nil && l == nil {
+		l, err = net.Listen("tcp", srv.Addr)
+	}
+	if err != nil {
+		api.ManagerService.emit(EventServerListening, api.app, err)
+		return err
+	}
+
+	api.lnMu.Lock()
+	api.ln = l
+	api.lnMu.Unlock()
+	api.listenOnce.Do(func() { close(api.listening) })
+	api.ManagerService.emit(EventServerListening, api.app, nil)
+
+	if api.TLSConfig != nil {
+		return srv.ServeTLS(l, api.tlsCert, api.tlsKey)
+	}
+	return srv.Serve(l)
+}
+
+// listenRedirect serves the HTTP redirect server srv, on the listener
+// inherited under redirectListener's name for the app after a graceful
+// restart if there is one.
+func (api *API) listenRedirect(srv *http.Server) error {
+	l, err := inheritedListener(redirectListener(api.app))
+	if err == nil && l == nil {
+		l, err = net.Listen("tcp", srv.Addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	api.lnMu.Lock()
+	api.redirectLn = l
+	api.lnMu.Unlock()
+	return srv.Serve(l)
+}
+
+// redirectListener names app's redirect listener among those handed over on
+// a graceful restart.
+func redirectListener(app string) string {
+	return app + ":redirect"
+}
+
+// Start serves the API in the background, as Serve does, returning once
+// the listener is bound or with the error which stopped it binding.  The
+// server shuts down gracefully when ctx is done or Shutdown is called.  An
+// API can only be started once.
+func (api *API) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	served := make(chan error, 1)
+
+	api.lifeMu.Lock()
+	if api.served != nil {
+		api.lifeMu.Unlock()
+		cancel()
+		return errors.New("Start: the API has already been started.")
+	}
+	api.stop, api.served = cancel, served
+	api.lifeMu.Unlock()
+
+	go func() {
+		served <- api.ManagerService.Serve(ctx, api)
+		close(served)
+	}()
+
+	select {
+	case <-api.listening:
+		return nil
+	case err := <-served:
+		cancel()
+		if err == nil {
+			err = errors.New("Start: the API stopped before listening.")
+		}
+		return err
+	}
+}
+
+// Shutdown stops an API started with Start: it stops accepting connections
+// and drains in-flight requests for up to shutdown_timeout.  When it was the
+// last API being served the manager also waits for workers and closes its
+// datastores.  It returns the server's error, or
+// ctx's error if ctx is done before shutdown completes.
+func (api *API) Shutdown(ctx context.Context) error {
+	api.lifeMu.Lock()
+	stop, served := api.stop, api.served
+	api.lifeMu.Unlock()
+
+	if served == nil {
+		return errors.New("Shutdown: the API has not been started.")
+	}
+	stop()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// BoundAddr returns the address the API is listening on, which reports the
+// actual port when configured with port 0, or nil before the server starts.
+// Wait on Listening to know when it is available.
+func (api *API) BoundAddr() net.Addr {
+	api.lnMu.Lock()
+	defer api.lnMu.Unlock()
+
+	if api.ln == nil {
+		return nil
+	}
+	return api.ln.Addr()
+}
+
+// Listening returns a channel which is closed once Daemonize has bound the
+// API's listener.
+func (api *API) Listening() <-chan struct{} {
+	return api.listening
+}
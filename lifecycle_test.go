@@ -0,0 +1,203 @@
+package governor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// startTestAPI creates and starts testapp listening on host, port 0.
+func startTestAPI(t *testing.T, host string) *API {
+	t.Helper()
+
+	ms := newTestManager(t, fmt.Sprintf("[testapp]\nhost = %q\nport = 0\n", host))
+	api, err := ms.createAPI("testapp")
+	if err != nil {
+		t.Fatalf("createAPI: %s", err)
+	}
+	if err := api.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		api.Shutdown(ctx)
+	})
+	return api
+}
+
+func TestBoundAddr(t *testing.T) {
+	cases := []struct {
+		name string
+		host string
+		ip   net.IP
+	}{
+		{"ipv4", "127.0.0.1", net.IPv4(127, 0, 0, 1)},
+		{"ipv6", "::1", net.IPv6loopback},
+		{"ipv6 brackets", "[::1]", net.IPv6loopback},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.ip.To4() == nil {
+				l, err := net.Listen("tcp", "[::1]:0")
+				if err != nil {
+					t.Skipf("IPv6 loopback unavailable: %s", err)
+				}
+				l.Close()
+			}
+
+			api := startTestAPI(t, c.host)
+			addr, ok := api.BoundAddr().(*net.TCPAddr)
+			if !ok {
+				t.Fatalf("BoundAddr() = %v, want a *net.TCPAddr", api.BoundAddr())
+			}
+			if !addr.IP.Equal(c.ip) {
+				t.Errorf("bound to %s, want %s", addr.IP, c.ip)
+			}
+			if addr.Port == 0 {
+				t.Errorf("bound to port 0, want the ephemeral port")
+			}
+
+			conn, err := net.Dial("tcp", addr.String())
+			if err != nil {
+				t.Fatalf("dial %s: %s", addr, err)
+			}
+			conn.Close()
+		})
+	}
+}
+
+func TestBoundAddrBeforeStart(t *testing.T) {
+	if addr := newTestAPI().BoundAddr(); addr != nil {
+		t.Errorf("BoundAddr() = %v before start, want nil", addr)
+	}
+}
+
+// isDone reports whether ch is closed.
+func isDone(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestShutdownLastAPIStopsManager(t *testing.T) {
+	ms := newTestManager(t, "[one]\nport = 0\n\n[two]\nport = 0\n")
+	var apis []*API
+	for _, app := range []string{"one", "two"} {
+		api, err := ms.createAPI(app)
+		if err != nil {
+			t.Fatalf("createAPI: %s", err)
+		}
+		if err := api.Start(context.Background()); err != nil {
+			t.Fatalf("Start: %s", err)
+		}
+		apis = append(apis, api)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := apis[0].Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %s", err)
+	}
+	if isDone(ms.Done()) {
+		t.Fatal("manager shut down while another API is serving")
+	}
+	if isDone(apis[1].context().Done()) {
+		t.Fatal("shutting down one API cancelled the other")
+	}
+
+	if err := apis[1].Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %s", err)
+	}
+	if !isDone(ms.Done()) {
+		t.Fatal("manager still running after the last API shut down")
+	}
+}
+
+func TestServeAfterShutdown(t *testing.T) {
+	ms := newTestManager(t, "[testapp]\nport = 0\n")
+	api, err := ms.createAPI("testapp")
+	if err != nil {
+		t.Fatalf("createAPI: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		served := make(chan error, 1)
+		go func() { served <- ms.Serve(ctx, api) }()
+
+		deadline := time.After(5 * time.Second)
+		for api.BoundAddr() == nil || api.context().Err() != nil {
+			select {
+			case err := <-served:
+				t.Fatalf("run %d: Serve returned early: %v", i, err)
+			case <-deadline:
+				t.Fatalf("run %d: API did not start", i)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		if isDone(ms.Done()) {
+			t.Fatalf("run %d: Serve started with the manager shut down", i)
+		}
+
+		cancel()
+		if err := <-served; err != nil {
+			t.Fatalf("run %d: Serve: %s", i, err)
+		}
+		if !isDone(ms.Done()) {
+			t.Fatalf("run %d: manager still running after Serve returned", i)
+		}
+	}
+}
+
+func TestDoneBeforeDrain(t *testing.T) {
+	ms := newTestManager(t, "[testapp]\nport = 0\n")
+	api, err := ms.createAPI("testapp")
+	if err != nil {
+		t.Fatalf("createAPI: %s", err)
+	}
+	started, release := make(chan struct{}), make(chan struct{})
+	api.GET("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	var stopped []string
+	if err := ms.StartWorkers("testapp", func(ctx context.Context) {
+		<-ctx.Done()
+		stopped = append(stopped, "worker")
+	}); err != nil {
+		t.Fatalf("StartWorkers: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := api.Start(ctx); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+	go http.Get("http://" + api.BoundAddr().String() + "/slow")
+	<-started
+
+	cancel()
+	select {
+	case <-ms.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Done not closed while a request is draining")
+	}
+
+	close(release)
+	shutdown, done := context.WithTimeout(context.Background(), 5*time.Second)
+	defer done()
+	if err := api.Shutdown(shutdown); err != nil {
+		t.Fatalf("Shutdown: %s", err)
+	}
+	if len(stopped) != 1 {
+		t.Errorf("workers stopped: %v, want one", stopped)
+	}
+}
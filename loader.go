@@ -0,0 +1,145 @@
+package governor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml"
+)
+
+// loadOptions controls how config files are read.
+type loadOptions struct {
+	// timeout bounds each file read
+	timeout time.Duration
+	// warnf reports problems which do not stop the load
+	warnf func(format string, args ...interface{})
+}
+
+// loadOptions returns the options the manager loads config files with.
+func (ms *ManagerService) loadOptions() loadOptions {
+	return loadOptions{timeout: ms.fileTimeout(), warnf: ms.Log().Warnf}
+}
+
+// loadConfigFile loads path and any files it names in a top-level include
+// array, returning the merged tree and the absolute paths of every file read.
+// Included paths are relative to the including file's directory.  Values in
+// the including file override included ones, and later includes override
+// earlier ones, so the result depends only on the include order and never on
+// map or filesystem iteration order.  With strict_includes = true, a file and
+// its includes must define disjoint top-level keys, so two files both
+// defining [myapp] is an error rather than a silent override.  Each file read
+// is bounded by opts.timeout.
+func loadConfigFile(path string, opts loadOptions) (*toml.Tree, []string, error) {
+	return loadIncludes(path, nil, opts)
+}
+
+func loadIncludes(path string, stack []string, opts loadOptions) (*toml.Tree, []string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	for _, p := range stack {
+		if p == abs {
+			return nil, nil, fmt.Errorf("Circular include: %s -> %s", strings.Join(stack, " -> "), abs)
+		}
+	}
+	stack = append(stack, abs)
+
+	data, err := readFile(path, opts.timeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to load '%s': %w", path, err)
+	}
+	if data, err = dedupeSections(path, data, opts.warnf); err != nil {
+		return nil, nil, err
+	}
+	tree, err := toml.LoadBytes(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to load '%s': %s", path, err)
+	}
+	strict := false
+	if v, ok := tree.Get("strict_includes").(bool); ok {
+		strict = v
+		tree.Delete("strict_includes")
+	}
+	if _, ok := tree.Get("strict_sections").(bool); ok {
+		// already applied by dedupeSections
+		tree.Delete("strict_sections")
+	}
+	if !tree.Has("include") {
+		return tree, []string{abs}, nil
+	}
+
+	var includes []string
+	switch v := tree.Get("include").(type) {
+	case string:
+		includes = []string{v}
+	case []interface{}:
+		for _, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("'%s': include must list file names.", path)
+			}
+			includes = append(includes, s)
+		}
+	default:
+		return nil, nil, fmt.Errorf("'%s': include must list file names.", path)
+	}
+	tree.Delete("include")
+
+	// fill from the last include first so later includes take precedence
+	dir := filepath.Dir(abs)
+	sources := []string{}
+	included := make([]*toml.Tree, len(includes))
+	definedIn := map[string]string{}
+	if strict {
+		for _, k := range tree.Keys() {
+			definedIn[k] = path
+		}
+	}
+	for i, inc := range includes {
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(dir, inc)
+		}
+		t, s, err := loadIncludes(inc, stack, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		if strict {
+			for _, k := range t.Keys() {
+				if other, ok := definedIn[k]; ok {
+					return nil, nil, fmt.Errorf("Configuration '%s' is defined in both '%s' and '%s' with strict_includes set.", k, other, inc)
+				}
+				definedIn[k] = inc
+			}
+		}
+		included[i] = t
+		sources = append(sources, s...)
+	}
+	for i := len(included) - 1; i >= 0; i-- {
+		fillTree(tree, included[i])
+	}
+
+	return tree, append(sources, abs), nil
+}
+
+// fillTree copies into dst every key of src it does not already define,
+// descending into tables both define.
+func fillTree(dst *toml.Tree, src *toml.Tree) {
+	for _, k := range src.Keys() {
+		key := []string{k}
+		sv := src.GetPath(key)
+		dv := dst.GetPath(key)
+
+		if dv == nil {
+			dst.SetPath(key, sv)
+			continue
+		}
+		dt, dok := dv.(*toml.Tree)
+		st, sok := sv.(*toml.Tree)
+		if dok && sok {
+			fillTree(dt, st)
+		}
+	}
+}
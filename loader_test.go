@@ -0,0 +1,115 @@
+package governor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFiles writes each name to dir with its contents.
+func writeConfigFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// permutations returns every ordering of items.
+func permutations(items []string) [][]string {
+	if len(items) <= 1 {
+		return [][]string{append([]string{}, items...)}
+	}
+	var out [][]string
+	for i := range items {
+		rest := append(append([]string{}, items[:i]...), items[i+1:]...)
+		for _, p := range permutations(rest) {
+			out = append(out, append([]string{items[i]}, p...))
+		}
+	}
+	return out
+}
+
+// loadWithIncludes writes a main config including names in order and
+// returns the merged tree as TOML text.
+func loadWithIncludes(t *testing.T, dir string, header string, names []string) string {
+	t.Helper()
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = fmt.Sprintf("%q", n)
+	}
+	main := filepath.Join(dir, "main.toml")
+	writeConfigFiles(t, dir, map[string]string{
+		"main.toml": header + "include = [" + strings.Join(quoted, ", ") + "]\n\n[main_app]\nport = 1\n",
+	})
+	tree, _, err := loadConfigFile(main, loadOptions{timeout: time.Second, warnf: t.Logf})
+	if err != nil {
+		t.Fatalf("loadConfigFile: %s", err)
+	}
+	return tree.String()
+}
+
+func TestIncludeOrderDisjoint(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"a.toml": "[a_app]\nport = 2\n\n[shared]\na = \"a\"\n",
+		"b.toml": "[b_app]\nport = 3\n\n[shared]\nb = \"b\"\n",
+		"c.toml": "[c_app]\nport = 4\n\n[shared]\nc = \"c\"\n",
+		"d.toml": "[d_app]\nhosts = [\"d1\", \"d2\"]\n",
+	})
+
+	var want string
+	for _, order := range permutations([]string{"a.toml", "b.toml", "c.toml", "d.toml"}) {
+		// repeat each order so map iteration has a chance to vary
+		for i := 0; i < 3; i++ {
+			got := loadWithIncludes(t, dir, "", order)
+			if want == "" {
+				want = got
+				continue
+			}
+			if got != want {
+				t.Fatalf("include order %v gave\n%s\nwant\n%s", order, got, want)
+			}
+		}
+	}
+}
+
+func TestIncludeOrderOverride(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"a.toml": "[shared]\nname = \"a\"\n",
+		"b.toml": "[shared]\nname = \"b\"\n",
+		"c.toml": "[shared]\nname = \"c\"\n",
+	})
+
+	for _, order := range permutations([]string{"a.toml", "b.toml", "c.toml"}) {
+		got := loadWithIncludes(t, dir, "", order)
+		last := strings.TrimSuffix(order[len(order)-1], ".toml")
+		if !strings.Contains(got, fmt.Sprintf("name = %q", last)) {
+			t.Errorf("include order %v gave\n%s\nwant name from %s", order, got, order[len(order)-1])
+		}
+	}
+}
+
+func TestStrictIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"a.toml":    "[a_app]\nport = 2\n",
+		"b.toml":    "[a_app]\nport = 3\n",
+		"main.toml": "strict_includes = true\ninclude = [\"a.toml\", \"b.toml\"]\n",
+	})
+
+	_, _, err := loadConfigFile(filepath.Join(dir, "main.toml"), loadOptions{timeout: time.Second, warnf: t.Logf})
+	if err == nil || !strings.Contains(err.Error(), "'a_app' is defined in both") {
+		t.Fatalf("got error %v, want one naming a_app", err)
+	}
+
+	got := loadWithIncludes(t, dir, "strict_includes = true\n", []string{"a.toml"})
+	if strings.Contains(got, "strict_includes") {
+		t.Errorf("strict_includes left in the merged config:\n%s", got)
+	}
+}
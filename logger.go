@@ -0,0 +1,247 @@
+package governor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Level is the severity of a log message.  The zero value is LevelInfo, which
+// is the default threshold.
+type Level int32
+
+const (
+	LevelDebug Level = iota - 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// levelNames maps the accepted log_level values to levels.
+var levelNames = map[string]Level{
+	"debug":   LevelDebug,
+	"info":    LevelInfo,
+	"warn":    LevelWarn,
+	"warning": LevelWarn,
+	"error":   LevelError,
+}
+
+// ParseLevel converts a level name (debug, info, warn or error) to a Level.
+func ParseLevel(name string) (Level, error) {
+	level, ok := levelNames[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return LevelInfo, fmt.Errorf("Unknown log level '%s'.", name)
+	}
+	return level, nil
+}
+
+// Logger is the logging interface used throughout governor.  Use SetLogger to
+// route governor's logs to another logging package; zap's SugaredLogger and
+// logrus's Logger and Entry already implement it.
+type Logger interface {
+	Debugf(format string, v ...interface{})
+	Infof(format string, v ...interface{})
+	Warnf(format string, v ...interface{})
+	Errorf(format string, v ...interface{})
+}
+
+// stdLogger is the default Logger, writing through the standard library.
+type stdLogger struct {
+	l *log.Logger
+}
+
+func (s stdLogger) Debugf(format string, v ...interface{}) { s.l.Printf("DEBUG "+format, v...) }
+func (s stdLogger) Infof(format string, v ...interface{})  { s.l.Printf("INFO "+format, v...) }
+func (s stdLogger) Warnf(format string, v ...interface{})  { s.l.Printf("WARN "+format, v...) }
+func (s stdLogger) Errorf(format string, v ...interface{}) { s.l.Printf("ERROR "+format, v...) }
+
+// jsonLogger is the default Logger with log_format = "json", writing one
+// JSON object per message for log collectors.
+type jsonLogger struct {
+	w  io.Writer
+	ms *ManagerService
+}
+
+func (j jsonLogger) write(level string, format string, v ...interface{}) {
+	line, _ := json.Marshal(struct {
+		Time    string `json:"time"`
+		Level   string `json:"level"`
+		Message string `json:"msg"`
+	}{j.ms.now().UTC().Format(time.RFC3339Nano), level, fmt.Sprintf(format, v...)})
+	j.w.Write(append(line, '\n'))
+}
+
+func (j jsonLogger) Debugf(format string, v ...interface{}) { j.write("debug", format, v...) }
+func (j jsonLogger) Infof(format string, v ...interface{})  { j.write("info", format, v...) }
+func (j jsonLogger) Warnf(format string, v ...interface{})  { j.write("warn", format, v...) }
+func (j jsonLogger) Errorf(format string, v ...interface{}) { j.write("error", format, v...) }
+
+// loggerHolder lets the logger be swapped atomically.
+type loggerHolder struct {
+	Logger
+}
+
+// levelLogger discards messages below the log level of app, or the
+// manager's when app is empty or sets none, before passing them on to the
+// configured Logger.
+type levelLogger struct {
+	ms  *ManagerService
+	app string
+}
+
+func (l levelLogger) enabled(level Level) bool {
+	if l.app != "" {
+		if levels, ok := l.ms.appLogLevels.Load().(map[string]Level); ok {
+			if threshold, ok := levels[l.app]; ok {
+				return threshold <= level
+			}
+		}
+	}
+	return Level(atomic.LoadInt32(&l.ms.logLevel)) <= level
+}
+
+func (l levelLogger) Debugf(format string, v ...interface{}) {
+	if l.enabled(LevelDebug) {
+		l.ms.baseLogger(l.app).Debugf(format, v...)
+	}
+}
+
+func (l levelLogger) Infof(format string, v ...interface{}) {
+	if l.enabled(LevelInfo) {
+		l.ms.baseLogger(l.app).Infof(format, v...)
+	}
+}
+
+func (l levelLogger) Warnf(format string, v ...interface{}) {
+	if l.enabled(LevelWarn) {
+		l.ms.baseLogger(l.app).Warnf(format, v...)
+	}
+}
+
+func (l levelLogger) Errorf(format string, v ...interface{}) {
+	if l.enabled(LevelError) {
+		l.ms.baseLogger(l.app).Errorf(format, v...)
+	}
+}
+
+// SetLogger replaces the logger used by the manager and its APIs.
+func (ms *ManagerService) SetLogger(l Logger) {
+	ms.logger.Store(loggerHolder{l})
+}
+
+// SetLogLevel changes the minimum level of messages which are logged, for
+// apps which set no log_level of their own.  It is safe to call while the
+// manager is serving requests.
+func (ms *ManagerService) SetLogLevel(name string) error {
+	level, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt32(&ms.logLevel, int32(level))
+	return nil
+}
+
+// Log returns the manager's logger, filtered by the configured log level.
+func (ms *ManagerService) Log() Logger {
+	if ms.parent != nil {
+		return ms.parent.Log()
+	}
+	return levelLogger{ms: ms}
+}
+
+// AppLog returns the logger for messages about app, filtered by the
+// log_level under [app], or the manager's level when it sets none.
+func (ms *ManagerService) AppLog(app string) Logger {
+	if ms.parent != nil {
+		return ms.parent.AppLog(app)
+	}
+	return levelLogger{ms: ms, app: app}
+}
+
+// baseLogger returns the configured logger, defaulting to the standard
+// library logger writing to stderr, or JSON lines with log_format = "json"
+// under app's heading or, when it sets none, at the top level.
+func (ms *ManagerService) baseLogger(app string) Logger {
+	if h, ok := ms.logger.Load().(loggerHolder); ok {
+		return h.Logger
+	}
+	useJSON := atomic.LoadInt32(&ms.logJSON) == 1
+	if formats, ok := ms.appLogJSON.Load().(map[string]bool); ok {
+		if v, ok := formats[app]; ok {
+			useJSON = v
+		}
+	}
+	if useJSON {
+		return jsonLogger{w: os.Stderr, ms: ms}
+	}
+	return stdLogger{log.New(os.Stderr, "", log.LstdFlags)}
+}
+
+// fatalf logs an error through the manager's logger and exits, for the
+// calls which predate returning errors.
+func (ms *ManagerService) fatalf(format string, v ...interface{}) {
+	ms.Log().Errorf(format, v...)
+	os.Exit(1)
+}
+
+// applyLogLevel sets the log level and format of the default logger from
+// the top-level log_level and log_format keys, if any, and each app's from
+// the log_level and log_format under its heading.
+func (ms *ManagerService) applyLogLevel() error {
+	levels := map[string]Level{}
+	formats := map[string]bool{}
+	for _, app := range ms.Apps() {
+		if _, ok := ms.lookupAppProperty(app, "log_format"); ok {
+			format, _ := ms.GetAppProperty(app, "log_format")
+			switch format {
+			case "text":
+				formats[app] = false
+			case "json":
+				formats[app] = true
+			default:
+				return fmt.Errorf("Configuration 'log_format' under [%s] heading must be \"text\" or \"json\".", app)
+			}
+		}
+
+		if _, ok := ms.lookupAppProperty(app, "log_level"); !ok {
+			continue
+		}
+		name, err := ms.GetAppProperty(app, "log_level")
+		if err != nil {
+			return fmt.Errorf("Configuration 'log_level' under [%s] heading must be a string.", app)
+		}
+		level, err := ParseLevel(name)
+		if err != nil {
+			return fmt.Errorf("Configuration 'log_level' under [%s] heading: %s", app, err)
+		}
+		levels[app] = level
+	}
+	ms.appLogLevels.Store(levels)
+	ms.appLogJSON.Store(formats)
+
+	if value, ok := ms.Get("log_format"); ok {
+		switch value {
+		case "text":
+			atomic.StoreInt32(&ms.logJSON, 0)
+		case "json":
+			atomic.StoreInt32(&ms.logJSON, 1)
+		default:
+			return fmt.Errorf("Configuration 'log_format' must be \"text\" or \"json\".")
+		}
+	}
+
+	value, ok := ms.Get("log_level")
+	if !ok {
+		return nil
+	}
+	name, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("Configuration 'log_level' must be a string.")
+	}
+	return ms.SetLogLevel(name)
+}
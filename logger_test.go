@@ -0,0 +1,159 @@
+package governor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordLogger records each message it is given, prefixed by its level.
+type recordLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *recordLogger) record(level string, format string, v ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, level+" "+fmt.Sprintf(format, v...))
+}
+
+func (r *recordLogger) Debugf(format string, v ...interface{}) { r.record("debug", format, v...) }
+func (r *recordLogger) Infof(format string, v ...interface{})  { r.record("info", format, v...) }
+func (r *recordLogger) Warnf(format string, v ...interface{})  { r.record("warn", format, v...) }
+func (r *recordLogger) Errorf(format string, v ...interface{}) { r.record("error", format, v...) }
+
+func (r *recordLogger) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.lines, "\n")
+}
+
+func TestAppLogLevel(t *testing.T) {
+	t.Setenv("FROM_ENV_LOG_LEVEL", "error")
+	ms := newTestManager(t, `log_level = "warn"
+
+[chatty]
+log_level = "debug"
+
+[quiet]
+log_level = "error"
+
+[from_env]
+log_level = "debug"
+
+[plain]
+`)
+	rec := &recordLogger{}
+	ms.SetLogger(rec)
+
+	cases := []struct {
+		app    string
+		logged []string
+		hidden []string
+	}{
+		{app: "chatty", logged: []string{"debug", "info", "warn"}},
+		{app: "quiet", logged: []string{"error"}, hidden: []string{"info", "warn"}},
+		{app: "from_env", logged: []string{"error"}, hidden: []string{"debug", "warn"}},
+		{app: "plain", logged: []string{"warn"}, hidden: []string{"debug", "info"}},
+		{app: "", logged: []string{"warn"}, hidden: []string{"info"}},
+	}
+	for _, c := range cases {
+		log := ms.AppLog(c.app)
+		for _, level := range append(append([]string{}, c.logged...), c.hidden...) {
+			switch level {
+			case "debug":
+				log.Debugf("[%s] %s", c.app, level)
+			case "info":
+				log.Infof("[%s] %s", c.app, level)
+			case "warn":
+				log.Warnf("[%s] %s", c.app, level)
+			case "error":
+				log.Errorf("[%s] %s", c.app, level)
+			}
+		}
+	}
+
+	got := rec.String()
+	for _, c := range cases {
+		for _, level := range c.logged {
+			if line := level + " [" + c.app + "] " + level; !strings.Contains(got, line) {
+				t.Errorf("missing %q in:\n%s", line, got)
+			}
+		}
+		for _, level := range c.hidden {
+			if line := level + " [" + c.app + "] " + level; strings.Contains(got, line) {
+				t.Errorf("%q was not filtered:\n%s", line, got)
+			}
+		}
+	}
+}
+
+func TestAppLogLevelInvalid(t *testing.T) {
+	for _, config := range []string{"[testapp]\nlog_level = \"loud\"\n", "[testapp]\nlog_level = 3\n"} {
+		path := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		err := (&ManagerService{}).InitManager(path)
+		if err == nil || !strings.Contains(err.Error(), "[testapp]") {
+			t.Errorf("%q: got error %v, want one naming [testapp]", config, err)
+		}
+	}
+}
+
+func TestAppLogFormat(t *testing.T) {
+	t.Setenv("ENV_TEXT_LOG_FORMAT", "text")
+	ms := newTestManager(t, `log_format = "json"
+
+[app_text]
+log_format = "text"
+
+[env_text]
+log_format = "json"
+
+[app_json]
+log_format = "json"
+
+[plain]
+`)
+
+	cases := []struct {
+		app      string
+		wantJSON bool
+	}{
+		{"app_text", false},
+		{"env_text", false},
+		{"app_json", true},
+		{"plain", true},
+		{"", true},
+	}
+	for _, c := range cases {
+		_, isJSON := ms.baseLogger(c.app).(jsonLogger)
+		if isJSON != c.wantJSON {
+			t.Errorf("[%s] JSON logger %v, want %v", c.app, isJSON, c.wantJSON)
+		}
+	}
+
+	ms = newTestManager(t, "[app_json]\nlog_format = \"json\"\n\n[plain]\n")
+	if _, ok := ms.baseLogger("app_json").(jsonLogger); !ok {
+		t.Error("[app_json] does not log JSON")
+	}
+	if _, ok := ms.baseLogger("plain").(stdLogger); !ok {
+		t.Error("[plain] does not default to text")
+	}
+}
+
+func TestAppLogFormatInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte("[testapp]\nlog_format = \"xml\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := (&ManagerService{}).InitManager(path)
+	if err == nil || !strings.Contains(err.Error(), "'log_format' under [testapp]") {
+		t.Errorf("got error %v, want one naming [testapp]", err)
+	}
+}
@@ -0,0 +1,229 @@
+package governor
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricsMiddleware is the name of the middleware installed by metrics, for
+// use with Exempt.
+const MetricsMiddleware = "metrics"
+
+// defaultMetricsPath is where metrics are served unless metrics_path is set.
+const defaultMetricsPath = "/metrics"
+
+// latencyBuckets are the upper bounds, in seconds, of the request duration
+// histogram, matching the Prometheus client defaults.
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// metricMethods are the request methods counted by name; any other is
+// counted as OTHER, so clients cannot inflate the number of series.
+var metricMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+}
+
+// metricsRegistry holds the request metrics of every API on the manager.
+type metricsRegistry struct {
+	mu       sync.Mutex
+	requests map[requestKey]uint64
+	latency  map[string]*histogram
+}
+
+type requestKey struct {
+	app    string
+	method string
+	code   int
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(seconds float64) {
+	for i, le := range latencyBuckets {
+		if seconds <= le {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// metricsRegistry returns the manager's registry, creating it.
+func (ms *ManagerService) metricsRegistry() *metricsRegistry {
+	ms.metricsOnce.Do(func() {
+		ms.metrics = &metricsRegistry{
+			requests: make(map[requestKey]uint64),
+			latency:  make(map[string]*histogram),
+		}
+	})
+	return ms.metrics
+}
+
+func (m *metricsRegistry) observe(app string, method string, code int, elapsed time.Duration) {
+	if !metricMethods[method] {
+		method = "OTHER"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{app, method, code}]++
+	h, ok := m.latency[app]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		m.latency[app] = h
+	}
+	h.observe(elapsed.Seconds())
+}
+
+// configureMetrics installs the metrics middleware and mounts the metrics
+// endpoint, at metrics_path, when metrics is enabled for app.
+func (ms *ManagerService) configureMetrics(api *API, app string) error {
+	if _, ok := ms.lookupAppProperty(app, "metrics"); !ok {
+		return nil
+	}
+
+	enabled, err := ms.GetAppPropertyBool(app, "metrics")
+	if err != nil || !enabled {
+		return err
+	}
+
+	path := defaultMetricsPath
+	if p, err := ms.GetAppProperty(app, "metrics_path"); err == nil {
+		path = "/" + strings.TrimPrefix(p, "/")
+	}
+
+	api.Use(MetricsMiddleware, api.Metrics())
+	api.GET(path, api.metricsHandler)
+	return nil
+}
+
+// Metrics returns middleware which counts requests by method and status,
+// and records their duration, for the API's metrics endpoint.
+func (api *API) Metrics() Middleware {
+	ms := api.ManagerService
+	registry := ms.metricsRegistry()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := ms.now()
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+			registry.observe(api.app, r.Method, sw.Status(), ms.now().Sub(start))
+		})
+	}
+}
+
+// metricsHandler serves the manager's request metrics, for every API which
+// records them, and the connection pool stats of each datastore, in the
+// Prometheus text format.
+func (api *API) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+	api.ManagerService.metricsRegistry().write(&b)
+	api.ManagerService.writePoolStats(&b)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+func (m *metricsRegistry) write(b *strings.Builder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, c := keys[i], keys[j]
+		if a.app != c.app {
+			return a.app < c.app
+		}
+		if a.method != c.method {
+			return a.method < c.method
+		}
+		return a.code < c.code
+	})
+
+	b.WriteString("# HELP governor_http_requests_total HTTP requests served, by app, method and status code.\n")
+	b.WriteString("# TYPE governor_http_requests_total counter\n")
+	for _, k := range keys {
+		fmt.Fprintf(b, "governor_http_requests_total{app=%s,method=%s,code=\"%d\"} %d\n", labelValue(k.app), labelValue(k.method), k.code, m.requests[k])
+	}
+
+	apps := make([]string, 0, len(m.latency))
+	for app := range m.latency {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+
+	b.WriteString("# HELP governor_http_request_duration_seconds HTTP request durations, by app.\n")
+	b.WriteString("# TYPE governor_http_request_duration_seconds histogram\n")
+	for _, app := range apps {
+		h := m.latency[app]
+		for i, le := range latencyBuckets {
+			fmt.Fprintf(b, "governor_http_request_duration_seconds_bucket{app=%s,le=\"%s\"} %d\n", labelValue(app), strconv.FormatFloat(le, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(b, "governor_http_request_duration_seconds_bucket{app=%s,le=\"+Inf\"} %d\n", labelValue(app), h.count)
+		fmt.Fprintf(b, "governor_http_request_duration_seconds_sum{app=%s} %s\n", labelValue(app), strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(b, "governor_http_request_duration_seconds_count{app=%s} %d\n", labelValue(app), h.count)
+	}
+}
+
+// writePoolStats writes the connection pool stats of each initialized gorm
+// datastore.  Apps sharing a pool report the same figures.
+func (ms *ManagerService) writePoolStats(b *strings.Builder) {
+	type poolStats struct {
+		app                  string
+		maxOpen, open, inUse int
+		idle                 int
+		waitCount            int64
+		waitDuration         time.Duration
+	}
+
+	ms.dbmu.RLock()
+	stats := make([]poolStats, 0, len(ms.DBConfig))
+	for app, dbc := range ms.DBConfig {
+		if dbc == nil || dbc.Connection == nil {
+			continue
+		}
+		s := dbc.Connection.DB().Stats()
+		stats = append(stats, poolStats{app, s.MaxOpenConnections, s.OpenConnections, s.InUse, s.Idle, s.WaitCount, s.WaitDuration})
+	}
+	ms.dbmu.RUnlock()
+	sort.Slice(stats, func(i, j int) bool { return stats[i].app < stats[j].app })
+
+	metrics := []struct {
+		name, kind string
+		value      func(s poolStats) string
+	}{
+		{"governor_db_max_open_connections", "gauge", func(s poolStats) string { return strconv.Itoa(s.maxOpen) }},
+		{"governor_db_open_connections", "gauge", func(s poolStats) string { return strconv.Itoa(s.open) }},
+		{"governor_db_in_use_connections", "gauge", func(s poolStats) string { return strconv.Itoa(s.inUse) }},
+		{"governor_db_idle_connections", "gauge", func(s poolStats) string { return strconv.Itoa(s.idle) }},
+		{"governor_db_wait_count_total", "counter", func(s poolStats) string { return strconv.FormatInt(s.waitCount, 10) }},
+		{"governor_db_wait_duration_seconds_total", "counter", func(s poolStats) string {
+			return strconv.FormatFloat(s.waitDuration.Seconds(), 'g', -1, 64)
+		}},
+	}
+	for _, m := range metrics {
+		fmt.Fprintf(b, "# TYPE %s %s\n", m.name, m.kind)
+		for _, s := range stats {
+			fmt.Fprintf(b, "%s{app=%s} %s\n", m.name, labelValue(s.app), m.value(s))
+		}
+	}
+}
+
+// labelValue quotes v as a Prometheus label value.
+func labelValue(v string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(v) + `"`
+}
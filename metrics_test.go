@@ -0,0 +1,108 @@
+package governor
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	ms := newTestManager(t, fmt.Sprintf(`[testapp]
+port = 0
+metrics = true
+metrics_path = "stats"
+dbdriver = "sqlite3"
+dbpath = %q
+`, filepath.Join(t.TempDir(), "metrics.db")))
+	t.Cleanup(func() { ms.Close() })
+	if err := ms.InitDatastore("testapp"); err != nil {
+		t.Fatalf("InitDatastore: %s", err)
+	}
+
+	// each reading of the clock advances it 10ms, the duration of a request
+	var mu sync.Mutex
+	now := time.Unix(0, 0)
+	ms.SetClock(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(10 * time.Millisecond)
+		return now
+	})
+
+	api, err := ms.createAPI("testapp")
+	if err != nil {
+		t.Fatalf("createAPI: %s", err)
+	}
+	api.GET("/thing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	h := api.newServer().Handler
+	for _, method := range []string{"GET", "GET", "POST", "BREW"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/thing", nil))
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/stats", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /stats = %d, want 200", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body, _ := io.ReadAll(w.Body)
+	for _, line := range []string{
+		`governor_http_requests_total{app="testapp",method="GET",code="418"} 2`,
+		`governor_http_requests_total{app="testapp",method="POST",code="405"} 1`,
+		`governor_http_requests_total{app="testapp",method="OTHER",code="405"} 1`,
+		`governor_http_request_duration_seconds_bucket{app="testapp",le="0.005"} 0`,
+		`governor_http_request_duration_seconds_bucket{app="testapp",le="0.01"} 4`,
+		`governor_http_request_duration_seconds_bucket{app="testapp",le="+Inf"} 4`,
+		`governor_http_request_duration_seconds_sum{app="testapp"} 0.04`,
+		`governor_http_request_duration_seconds_count{app="testapp"} 4`,
+		`governor_db_open_connections{app="testapp"} `,
+		`governor_db_wait_count_total{app="testapp"} 0`,
+	} {
+		if !strings.Contains(string(body), line+"\n") && !strings.Contains(string(body), "\n"+line) {
+			t.Errorf("metrics missing %q:\n%s", line, body)
+		}
+	}
+}
+
+func TestMetricsDisabled(t *testing.T) {
+	for _, config := range []string{"", "metrics = false"} {
+		ms := newTestManager(t, "[testapp]\nport = 0\n"+config+"\n")
+		api, err := ms.createAPI("testapp")
+		if err != nil {
+			t.Fatalf("createAPI: %s", err)
+		}
+		w := httptest.NewRecorder()
+		api.newServer().Handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%q: GET /metrics = %d, want 404", config, w.Code)
+		}
+	}
+
+	ms := newTestManager(t, "[testapp]\nport = 0\nmetrics = \"sometimes\"\n")
+	if _, err := ms.createAPI("testapp"); err == nil || !strings.Contains(err.Error(), "metrics") {
+		t.Errorf("got error %v, want one naming metrics", err)
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	for v, want := range map[string]string{
+		"app":       `"app"`,
+		`a"b`:       `"a\"b"`,
+		`a\b`:       `"a\\b"`,
+		"line\nend": `"line\nend"`,
+	} {
+		if got := labelValue(v); got != want {
+			t.Errorf("labelValue(%q) = %s, want %s", v, got, want)
+		}
+	}
+}
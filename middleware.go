@@ -0,0 +1,93 @@
+package governor
+
+import (
+	"net/http"
+)
+
+// Middleware wraps an http.Handler with additional behaviour.
+type Middleware func(http.Handler) http.Handler
+
+// namedMiddleware associates a middleware with the name used to exempt paths
+// from it.
+type namedMiddleware struct {
+	name string
+	mw   Middleware
+}
+
+// Use appends a named middleware to the API's chain.  Middleware runs in the
+// order it was added, around every request served by Daemonize.
+func (api *API) Use(name string, mw Middleware) {
+	api.middleware = append(api.middleware, namedMiddleware{name: name, mw: mw})
+}
+
+// Exempt excludes requests for path from the named middleware, or from all
+// middleware when no names are given.
+func (api *API) Exempt(path string, names ...string) {
+	if api.exempt == nil {
+		api.exempt = make(map[string][]string)
+	}
+	if len(names) == 0 {
+		api.exempt[path] = nil
+		return
+	}
+	if current, ok := api.exempt[path]; ok && current == nil {
+		// already exempt from everything
+		return
+	}
+	api.exempt[path] = append(api.exempt[path], names...)
+}
+
+// isExempt reports whether requests for path skip the named middleware.
+func (api *API) isExempt(path string, name string) bool {
+	names, ok := api.exempt[path]
+	if !ok {
+		return false
+	}
+	if names == nil {
+		return true
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// handler returns the router wrapped in the API's middleware chain.
+func (api *API) handler() http.Handler {
+	h := http.Handler(api.WebService.Router)
+
+	for i := len(api.middleware) - 1; i >= 0; i-- {
+		m := api.middleware[i]
+		next := h
+		wrapped := m.mw(next)
+		h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if api.isExempt(r.URL.Path, m.name) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+
+	return h
+}
+
+// configureExempt reads middleware_exempt, a list of paths which skip all
+// middleware, for app.
+func (ms *ManagerService) configureExempt(api *API, app string) error {
+	if _, ok := ms.lookupAppProperty(app, "middleware_exempt"); !ok {
+		return nil
+	}
+
+	paths, err := ms.GetAppPropertyStrings(app, "middleware_exempt")
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		api.Exempt(p)
+	}
+
+	return nil
+}
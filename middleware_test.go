@@ -0,0 +1,76 @@
+package governor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// seenMiddleware adds name to the X-Seen header of each response it wraps.
+func seenMiddleware(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Seen", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestExempt(t *testing.T) {
+	api := newTestAPI()
+	api.Use("a", seenMiddleware("a"))
+	api.Use("b", seenMiddleware("b"))
+	api.Exempt("/one", "a")
+	api.Exempt("/all")
+	// naming middleware for a path exempt from everything changes nothing
+	api.Exempt("/all", "b")
+	api.Exempt("/both", "a")
+	api.Exempt("/both", "b")
+
+	h := api.handler()
+	for path, want := range map[string]string{
+		"/other": "a,b",
+		"/one":   "b",
+		"/all":   "",
+		"/both":  "",
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if got := strings.Join(w.Header().Values("X-Seen"), ","); got != want {
+			t.Errorf("%s ran %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestMiddlewareExemptConfig(t *testing.T) {
+	ms := newTestManager(t, `[testapp]
+port = 0
+base_path = "/api"
+access_log = true
+middleware_exempt = ["/healthz"]
+`)
+	logs := &recordLogger{}
+	ms.SetLogger(logs)
+	api, err := ms.createAPI("testapp")
+	if err != nil {
+		t.Fatalf("createAPI: %s", err)
+	}
+
+	// exempt paths are matched without the base path, as routes are
+	h := api.mountBasePath(api.handler())
+	for _, path := range []string{"/api/healthz", "/api/other"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	if got := logs.String(); strings.Contains(got, "/healthz") || !strings.Contains(got, "info GET /other 404") {
+		t.Errorf("access log = %q, want only /other", got)
+	}
+}
+
+func TestMiddlewareExemptInvalid(t *testing.T) {
+	ms := newTestManager(t, "[testapp]\nport = 0\nmiddleware_exempt = [1]\n")
+	if _, err := ms.createAPI("testapp"); err == nil || !strings.Contains(err.Error(), "'middleware_exempt' under [testapp] heading must contain only strings.") {
+		t.Errorf("got error %v", err)
+	}
+}
@@ -0,0 +1,253 @@
+package governor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// MigrationRecord is a row of the schema_migrations table, recording a
+// migration which has been applied for an app.  Apps sharing a database
+// keep their versions apart by app.
+type MigrationRecord struct {
+	App       string    `gorm:"primary_key" json:"app"`
+	Version   string    `gorm:"primary_key" json:"version"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// TableName sets the table gorm uses for MigrationRecord.
+func (MigrationRecord) TableName() string {
+	return "schema_migrations"
+}
+
+// RunSQLMigrations applies the .sql files in dir to app's datastore in lexical
+// order.  Each file's name without the extension is its version, which is
+// recorded in schema_migrations once applied so it is skipped on later runs.
+// Every file runs in its own transaction; the first failure stops the run.
+// Files containing several statements need a driver which accepts them in a
+// single Exec (for mysql, multiStatements=true).
+func (ms *ManagerService) RunSQLMigrations(app string, dir string) error {
+	db, err := ms.DB(app)
+	if err != nil {
+		return err
+	}
+
+	if err := ms.dbError(app, db.AutoMigrate(&MigrationRecord{}).Error); err != nil {
+		return fmt.Errorf("RunSQLMigrations: unable to create schema_migrations: %s", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("RunSQLMigrations: %s", err)
+	}
+
+	applied, err := appliedVersions(db, app)
+	if err != nil {
+		return ms.dbError(app, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		version := strings.TrimSuffix(entry.Name(), ".sql")
+		if applied[version] {
+			continue
+		}
+
+		script, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("RunSQLMigrations: %s", err)
+		}
+
+		if err := ms.applyMigration(app, db, version, sqlMigration(string(script))); err != nil {
+			return fmt.Errorf("RunSQLMigrations: %s", err)
+		}
+		ms.AppLog(app).Infof("RunSQLMigrations: applied '%s' for [%s]", version, app)
+	}
+
+	return nil
+}
+
+// applyMigration runs up in a transaction and records version as applied
+// in the same transaction.
+func (ms *ManagerService) applyMigration(app string, db *gorm.DB, version string, up func(tx *gorm.DB) error) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := up(tx); err != nil {
+			return err
+		}
+		return tx.Create(&MigrationRecord{App: app, Version: version, AppliedAt: ms.now()}).Error
+	})
+	if err = ms.dbError(app, err); err != nil {
+		return fmt.Errorf("migration '%s' for [%s] failed: %s", version, app, err)
+	}
+	return nil
+}
+
+// sqlMigration returns a migration which executes script.
+func sqlMigration(script string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return tx.Exec(script).Error
+	}
+}
+
+// migration is a versioned migration registered for an app.
+type migration struct {
+	version string
+	up      func(tx *gorm.DB) error
+}
+
+// appMigrations holds the models and migrations registered for an app.
+type appMigrations struct {
+	models     []interface{}
+	migrations []migration
+}
+
+// RegisterModels registers gorm models for Migrate to auto-migrate on app's
+// datastore, e.g. gms.RegisterModels("example_app", &User{}, &Post{}).
+// AutoMigrate only creates missing tables, columns and indexes; it never
+// changes or drops existing ones.
+func (ms *ManagerService) RegisterModels(app string, models ...interface{}) {
+	ms.migrationsMu.Lock()
+	defer ms.migrationsMu.Unlock()
+
+	am := ms.appMigrations(app)
+	am.models = append(am.models, models...)
+}
+
+// RegisterMigration registers a versioned migration for Migrate to apply to
+// app's datastore.  Migrations run in lexical order of version, after the
+// registered models are auto-migrated, each in its own transaction, and are
+// recorded in schema_migrations alongside those of RunSQLMigrations so each
+// runs once.
+func (ms *ManagerService) RegisterMigration(app string, version string, up func(tx *gorm.DB) error) {
+	ms.migrationsMu.Lock()
+	defer ms.migrationsMu.Unlock()
+
+	am := ms.appMigrations(app)
+	am.migrations = append(am.migrations, migration{version: version, up: up})
+}
+
+// RegisterSQLMigration registers a versioned migration which executes script,
+// as RegisterMigration does.
+func (ms *ManagerService) RegisterSQLMigration(app string, version string, script string) {
+	ms.RegisterMigration(app, version, sqlMigration(script))
+}
+
+// appMigrations returns app's registry entry, creating it.  The caller holds
+// migrationsMu.
+func (ms *ManagerService) appMigrations(app string) *appMigrations {
+	if ms.migrations == nil {
+		ms.migrations = make(map[string]*appMigrations)
+	}
+	am, ok := ms.migrations[app]
+	if !ok {
+		am = &appMigrations{}
+		ms.migrations[app] = am
+	}
+	return am
+}
+
+// Migrate brings app's datastore schema up to date: it auto-migrates the
+// models registered with RegisterModels, then applies the registered
+// migrations which schema_migrations does not record, stopping at the first
+// failure.  Running it again is a no-op for migrations already applied.
+// With automigrate = true under [app], InitDatastore calls it after
+// connecting.
+func (ms *ManagerService) Migrate(app string) error {
+	db, err := ms.DB(app)
+	if err != nil {
+		return fmt.Errorf("Migrate: %s", err)
+	}
+
+	ms.migrationsMu.Lock()
+	var models []interface{}
+	var migrations []migration
+	if am, ok := ms.migrations[app]; ok {
+		models = append(models, am.models...)
+		migrations = append(migrations, am.migrations...)
+	}
+	ms.migrationsMu.Unlock()
+
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].version == migrations[i-1].version {
+			return fmt.Errorf("Migrate: migration '%s' for [%s] is registered more than once.", migrations[i].version, app)
+		}
+	}
+
+	if err := ms.dbError(app, db.AutoMigrate(&MigrationRecord{}).Error); err != nil {
+		return fmt.Errorf("Migrate: unable to create schema_migrations: %s", err)
+	}
+	if len(models) > 0 {
+		if err := ms.dbError(app, db.AutoMigrate(models...).Error); err != nil {
+			return fmt.Errorf("Migrate: auto-migrating models for [%s] failed: %s", app, err)
+		}
+	}
+
+	applied, err := appliedVersions(db, app)
+	if err != nil {
+		return fmt.Errorf("Migrate: %s", ms.dbError(app, err))
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		if err := ms.applyMigration(app, db, m.version, m.up); err != nil {
+			return fmt.Errorf("Migrate: %s", err)
+		}
+		ms.AppLog(app).Infof("Migrate: applied '%s' for [%s]", m.version, app)
+	}
+
+	return nil
+}
+
+// autoMigrate runs Migrate for app when automigrate is enabled.
+func (ms *ManagerService) autoMigrate(app string) error {
+	if enabled, err := ms.GetAppPropertyBool(app, "automigrate"); err != nil || !enabled {
+		return nil
+	}
+	return ms.Migrate(app)
+}
+
+// appliedVersions returns the set of versions recorded in schema_migrations
+// for app.
+func appliedVersions(db *gorm.DB, app string) (map[string]bool, error) {
+	var records []MigrationRecord
+	if err := db.Where("app = ?", app).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("Unable to read schema_migrations: %s", err)
+	}
+
+	applied := make(map[string]bool, len(records))
+	for _, r := range records {
+		applied[r.Version] = true
+	}
+	return applied, nil
+}
+
+// MigrationStatus returns the migrations recorded for app in its
+// schema_migrations table, oldest first.  An empty slice is returned when no migrations have
+// run.
+func (ms *ManagerService) MigrationStatus(app string) ([]MigrationRecord, error) {
+	db, err := ms.DB(app)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []MigrationRecord{}
+	if !db.HasTable(&MigrationRecord{}) {
+		return records, nil
+	}
+	if err := ms.dbError(app, db.Where("app = ?", app).Order("applied_at, version").Find(&records).Error); err != nil {
+		return nil, fmt.Errorf("MigrationStatus: unable to read schema_migrations: %s", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,69 @@
+package governor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateSharedDatabase(t *testing.T) {
+	ms := sqliteManager(t, "one", "two")
+	for _, app := range []string{"one", "two"} {
+		ms.RegisterSQLMigration(app, "0001_init", "CREATE TABLE "+app+"_things (id integer)")
+	}
+
+	for _, app := range []string{"one", "two"} {
+		if err := ms.Migrate(app); err != nil {
+			t.Fatalf("Migrate: %s", err)
+		}
+	}
+
+	db, err := ms.DB("one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"one_things", "two_things"} {
+		if !db.HasTable(table) {
+			t.Errorf("%s was not created", table)
+		}
+	}
+
+	for _, app := range []string{"one", "two"} {
+		records, err := ms.MigrationStatus(app)
+		if err != nil {
+			t.Fatalf("MigrationStatus: %s", err)
+		}
+		if len(records) != 1 || records[0].App != app || records[0].Version != "0001_init" {
+			t.Errorf("[%s] records = %+v", app, records)
+		}
+		// a second run applies nothing
+		if err := ms.Migrate(app); err != nil {
+			t.Errorf("[%s] Migrate again: %s", app, err)
+		}
+	}
+}
+
+func TestRunSQLMigrationsSharedDatabase(t *testing.T) {
+	ms := sqliteManager(t, "one", "two")
+	for _, app := range []string{"one", "two"} {
+		dir := filepath.Join(t.TempDir(), app)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		script := []byte("CREATE TABLE " + app + "_rows (id integer)")
+		if err := os.WriteFile(filepath.Join(dir, "0001_init.sql"), script, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ms.RunSQLMigrations(app, dir); err != nil {
+			t.Fatalf("[%s] RunSQLMigrations: %s", app, err)
+		}
+	}
+
+	db, err := ms.DB("two")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !db.HasTable("one_rows") || !db.HasTable("two_rows") {
+		t.Error("a shared version was skipped for the second app")
+	}
+}
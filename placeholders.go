@@ -0,0 +1,110 @@
+package governor
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// placeholder matches a ${...} token left in a config value.
+var placeholder = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// CheckPlaceholders looks for string values in every app section, after
+// environment overrides, which still contain ${...} placeholders, such as a
+// dbpassword of "${DB_PASSWORD}" shipped without its variable.  sqlite3
+// dbpath values are expanded from the environment when connecting, so there
+// only placeholders naming unset variables count, as do ${scheme:reference}
+// tokens which fail to resolve.  Each offending key is logged as a warning,
+// or with strict set returned in a *ValidationError.  Loading a
+// configuration runs it, see checkLoadedPlaceholders.
+func (ms *ManagerService) CheckPlaceholders(strict bool) error {
+	report := &ValidationError{}
+	for _, app := range ms.Apps() {
+		ms.placeholderProblems(app, report)
+	}
+	if len(report.Problems) == 0 {
+		return nil
+	}
+
+	report.sort()
+	if strict {
+		return report
+	}
+	for _, p := range report.Problems {
+		ms.AppLog(p.App).Warnf("Configuration [%s] %s: %s", p.App, p.Property, p.Problem)
+	}
+	return nil
+}
+
+// checkLoadedPlaceholders runs CheckPlaceholders on a newly loaded
+// configuration, strictly when it sets strict_placeholders = true at the top
+// level.
+func (ms *ManagerService) checkLoadedPlaceholders() error {
+	v, _ := ms.Get("strict_placeholders")
+	strict, _ := v.(bool)
+	return ms.CheckPlaceholders(strict)
+}
+
+// placeholderProblems adds a problem to report for each key of app holding
+// an unresolved placeholder.
+func (ms *ManagerService) placeholderProblems(app string, report *ValidationError) {
+	section, err := ms.rawAppSection(app)
+	if err != nil {
+		return
+	}
+	// tokens which fail to resolve are left in place and reported below
+	ms.interpolateMap(section)
+
+	walkStrings("", section, func(key string, value string) {
+		tokens := placeholder.FindAllStringSubmatch(value, -1)
+		unresolved := []string{}
+		for _, t := range tokens {
+			if key == "dbpath" {
+				if _, set := os.LookupEnv(t[1]); set {
+					continue
+				}
+			}
+			unresolved = append(unresolved, t[0])
+		}
+		if len(unresolved) > 0 {
+			report.add(app, key, fmt.Sprintf("unresolved placeholder %s", strings.Join(unresolved, ", ")))
+		}
+	})
+}
+
+// walkStrings calls fn with the dotted key of every string in m, including
+// those in nested tables and arrays, in key order.
+func walkStrings(prefix string, m map[string]interface{}, fn func(key string, value string)) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		walkValue(key, m[k], fn)
+	}
+}
+
+func walkValue(key string, v interface{}, fn func(key string, value string)) {
+	switch t := v.(type) {
+	case string:
+		fn(key, t)
+	case map[string]interface{}:
+		walkStrings(key, t, fn)
+	case []interface{}:
+		for i, e := range t {
+			walkValue(fmt.Sprintf("%s[%d]", key, i), e, fn)
+		}
+	case []map[string]interface{}:
+		for i, e := range t {
+			walkStrings(fmt.Sprintf("%s[%d]", key, i), e, fn)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package governor
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadChecksPlaceholders(t *testing.T) {
+	t.Setenv("PLACEHOLDER_SET", "value")
+	cases := []struct {
+		name    string
+		config  string
+		warned  bool
+		wantErr bool
+	}{
+		{name: "resolved", config: "strict_placeholders = true\n\n[testapp]\ndbuser = \"${env:PLACEHOLDER_SET}\"\n"},
+		{name: "warning", config: "[testapp]\ndbpassword = \"${DB_PASSWORD}\"\n", warned: true},
+		{name: "strict", config: "strict_placeholders = true\n\n[testapp]\ndbpassword = \"${DB_PASSWORD}\"\n", wantErr: true},
+		{name: "strict unresolved scheme", config: "strict_placeholders = true\n\n[testapp]\ndbpassword = \"${nope:x}\"\n", wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.toml")
+			if err := os.WriteFile(path, []byte(c.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			rec := &recordLogger{}
+			ms := &ManagerService{}
+			ms.SetLogger(rec)
+
+			err := ms.InitManager(path)
+			if !c.wantErr {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if c.warned && !strings.Contains(rec.String(), "warn Configuration [testapp] dbpassword") {
+					t.Errorf("no warning logged, got %q", rec.String())
+				}
+				return
+			}
+
+			var report *ValidationError
+			if !errors.As(err, &report) {
+				t.Fatalf("got error %v, want a *ValidationError", err)
+			}
+			if len(report.Problems) != 1 || report.Problems[0].App != "testapp" || report.Problems[0].Property != "dbpassword" {
+				t.Errorf("problems = %+v, want testapp dbpassword", report.Problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package governor
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/lakesite/ls-superbase"
+)
+
+// sharedPool is a gorm connection used by every app whose datastore resolves
+// to the same DSN.  The connection is closed when the last app releases it.
+type sharedPool struct {
+	key  string
+	conn *gorm.DB
+	refs int
+}
+
+// poolKey identifies the database dbc connects to, or returns "" for
+// datastores which must not be shared, such as in-memory sqlite databases.
+func poolKey(dbc *superbase.DBConfig, params map[string]string) string {
+	if dbc.Driver == "sqlite3" {
+		if dbc.Path == "" || dbc.Path == ":memory:" {
+			return ""
+		}
+		return dbc.Driver + " " + dbc.Path
+	}
+	return dbc.Driver + " " + postgresDSN(dbc, params)
+}
+
+// acquirePool takes a reference on the live pool for key, if there is one,
+// skipping own when the caller is its only holder.
+func (ms *ManagerService) acquirePool(key string, own *sharedPool) *sharedPool {
+	if key == "" {
+		return nil
+	}
+
+	ms.dbmu.Lock()
+	p := ms.pools[key]
+	if p == own && p != nil && p.refs <= 1 {
+		p = nil
+	}
+	if p != nil {
+		p.refs++
+	}
+	ms.dbmu.Unlock()
+
+	if p != nil && p.conn.DB().Ping() != nil {
+		ms.releasePool(p)
+		return nil
+	}
+	return p
+}
+
+// newPool registers conn as the pool for key, holding one reference.  A
+// previous pool for key stays open until the apps using it release it.
+func (ms *ManagerService) newPool(key string, conn *gorm.DB) *sharedPool {
+	if key == "" {
+		return nil
+	}
+
+	p := &sharedPool{key: key, conn: conn, refs: 1}
+	ms.dbmu.Lock()
+	if ms.pools == nil {
+		ms.pools = make(map[string]*sharedPool)
+	}
+	ms.pools[key] = p
+	ms.dbmu.Unlock()
+	return p
+}
+
+// releasePool drops a reference on p, closing its connection with the last.
+func (ms *ManagerService) releasePool(p *sharedPool) {
+	ms.dbmu.Lock()
+	p.refs--
+	last := p.refs <= 0
+	if last && ms.pools[p.key] == p {
+		delete(ms.pools, p.key)
+	}
+	ms.dbmu.Unlock()
+
+	if last {
+		if err := p.conn.Close(); err != nil {
+			ms.Log().Warnf("InitDatastore: closing shared connection: %s", err)
+		}
+	}
+}
+
+// releaseConnection gives up app's previous gorm connection, dropping its
+// reference on a shared pool or closing a connection it held alone.
+func (ms *ManagerService) releaseConnection(app string, dbc *superbase.DBConfig, pool *sharedPool) {
+	switch {
+	case pool != nil:
+		ms.releasePool(pool)
+	case dbc != nil && dbc.Connection != nil:
+		if err := dbc.Connection.Close(); err != nil {
+			ms.AppLog(app).Warnf("InitDatastore: closing previous [%s] connection: %s", app, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package governor
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// sqliteManager returns a manager where each of apps uses the sqlite
+// database at the same path.
+func sqliteManager(t *testing.T, apps ...string) *ManagerService {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "shared.db")
+	config := ""
+	for _, app := range apps {
+		config += fmt.Sprintf("[%s]\ndbdriver = \"sqlite3\"\ndbpath = %q\n\n", app, path)
+	}
+	ms := newTestManager(t, config)
+	for _, app := range apps {
+		if err := ms.InitDatastore(app); err != nil {
+			t.Fatalf("InitDatastore: %s", err)
+		}
+	}
+	t.Cleanup(func() { ms.Close() })
+	return ms
+}
+
+// appDB returns the database handle behind app's gorm connection.
+func appDB(ms *ManagerService, app string) *sql.DB {
+	ms.dbmu.RLock()
+	defer ms.dbmu.RUnlock()
+	return ms.DBConfig[app].Connection.DB()
+}
+
+func TestReinitDatastoreReconnects(t *testing.T) {
+	ms := sqliteManager(t, "one")
+	before := appDB(ms, "one")
+
+	if err := ms.ReinitDatastore("one"); err != nil {
+		t.Fatalf("ReinitDatastore: %s", err)
+	}
+	if appDB(ms, "one") == before {
+		t.Fatal("ReinitDatastore rejoined the pool no other app holds")
+	}
+	if err := before.Ping(); err == nil {
+		t.Error("the replaced connection is still open")
+	}
+	if err := appDB(ms, "one").Ping(); err != nil {
+		t.Errorf("new connection: %s", err)
+	}
+}
+
+func TestReinitDatastoreRejoinsSharedPool(t *testing.T) {
+	ms := sqliteManager(t, "one", "two")
+	before := appDB(ms, "two")
+	if appDB(ms, "one") != before {
+		t.Fatal("apps on the same database do not share a pool")
+	}
+
+	if err := ms.ReinitDatastore("one"); err != nil {
+		t.Fatalf("ReinitDatastore: %s", err)
+	}
+	if appDB(ms, "one") != before {
+		t.Error("ReinitDatastore reopened a pool another app holds")
+	}
+	if err := before.Ping(); err != nil {
+		t.Errorf("the shared connection was closed: %s", err)
+	}
+}
+
+func TestReloadFailedReinitKeepsConnection(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	write := func(config string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(fmt.Sprintf("[one]\ndbdriver = \"sqlite3\"\ndbpath = %q\n", filepath.Join(dir, "one.db")))
+	ms := &ManagerService{}
+	if err := ms.InitManager(path); err != nil {
+		t.Fatalf("InitManager: %s", err)
+	}
+	if err := ms.InitDatastore("one"); err != nil {
+		t.Fatalf("InitDatastore: %s", err)
+	}
+	t.Cleanup(func() { ms.Close() })
+	before := appDB(ms, "one")
+
+	// nothing listens on port 1, so the new connection fails
+	write("[one]\ndbdriver = \"postgres\"\ndbserver = \"127.0.0.1\"\ndbport = 1\ndatabase = \"app\"\ndbuser = \"user\"\n")
+	change, err := ms.Reload()
+	if err == nil || change.Err == nil {
+		t.Fatalf("Reload succeeded, want the datastore to fail")
+	}
+
+	if _, err := ms.DB("one"); err != nil {
+		t.Fatalf("DB after the failed reload: %s", err)
+	}
+	if appDB(ms, "one") != before {
+		t.Error("the failed reload replaced the connection")
+	}
+	if err := before.Ping(); err != nil {
+		t.Errorf("the previous connection was closed: %s", err)
+	}
+}
@@ -0,0 +1,248 @@
+package governor
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml"
+)
+
+// lookupAppProperty returns the value of property for app, as
+// resolveAppProperty does, logging any interpolation failure and returning
+// the value with the failed tokens left in place.
+func (ms *ManagerService) lookupAppProperty(app string, property string) (interface{}, bool) {
+	value, ok, err := ms.resolveAppProperty(app, property)
+	if err != nil {
+		ms.Log().Warnf("%s", err)
+	}
+	return value, ok
+}
+
+// resolveAppProperty returns the value of property for app.  The
+// APPNAME_PROPERTY environment variable, when set, overrides the configuration
+// tree and is always returned as a string.  A WithEnvironment view checks the
+// [app.env] section before [app].  ${scheme:reference} tokens in strings are
+// then interpolated, see RegisterResolver.
+func (ms *ManagerService) resolveAppProperty(app string, property string) (interface{}, bool, error) {
+	value, ok := ms.rawAppProperty(app, property)
+	if !ok {
+		return nil, false, nil
+	}
+
+	value, key, err := ms.interpolateValue(value)
+	if err != nil {
+		return value, true, fmt.Errorf("Configuration '%s' under [%s] heading: %s", joinKey(property, key), app, err)
+	}
+	return value, true, nil
+}
+
+// rawAppProperty looks up property for app without interpolation.
+func (ms *ManagerService) rawAppProperty(app string, property string) (interface{}, bool) {
+	if v, ok := os.LookupEnv(envKey(app, property)); ok {
+		return v, true
+	}
+
+	if ms.env != "" {
+		if v, ok := ms.Get(app + "." + ms.env + "." + property); ok {
+			return v, true
+		}
+	}
+
+	return ms.Get(app + "." + property)
+}
+
+// envKey follows the APPNAME_PROPERTY convention used for environment overrides.
+func envKey(app string, property string) string {
+	return strings.ToUpper(app + "_" + property)
+}
+
+// GetAppPropertyInt gets the property for app as an int.  Native TOML integers
+// and numeric strings (e.g. from an environment override) are both accepted.
+func (ms *ManagerService) GetAppPropertyInt(app string, property string) (int, error) {
+	value, ok := ms.lookupAppProperty(app, property)
+	if !ok {
+		return 0, fmt.Errorf("Configuration missing '%s' section under [%s] heading.", property, app)
+	}
+
+	switch v := value.(type) {
+	case int64:
+		return int(v), nil
+	case string:
+		i, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("Configuration '%s' under [%s] heading is not an integer: '%s'.", property, app, v)
+		}
+		return i, nil
+	}
+	return 0, fmt.Errorf("Configuration '%s' under [%s] heading is not an integer.", property, app)
+}
+
+// GetAppPropertyBool gets the property for app as a bool.  Native TOML
+// booleans and strings accepted by strconv.ParseBool are both accepted.
+func (ms *ManagerService) GetAppPropertyBool(app string, property string) (bool, error) {
+	value, ok := ms.lookupAppProperty(app, property)
+	if !ok {
+		return false, fmt.Errorf("Configuration missing '%s' section under [%s] heading.", property, app)
+	}
+
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return false, fmt.Errorf("Configuration '%s' under [%s] heading is not a boolean: '%s'.", property, app, v)
+		}
+		return b, nil
+	}
+	return false, fmt.Errorf("Configuration '%s' under [%s] heading is not a boolean.", property, app)
+}
+
+// GetAppPropertyDuration gets the property for app as a time.Duration.
+// Strings are parsed with time.ParseDuration (e.g. "5s"), while integers,
+// native or as strings, are treated as a number of seconds.
+func (ms *ManagerService) GetAppPropertyDuration(app string, property string) (time.Duration, error) {
+	value, ok := ms.lookupAppProperty(app, property)
+	if !ok {
+		return 0, fmt.Errorf("Configuration missing '%s' section under [%s] heading.", property, app)
+	}
+
+	switch v := value.(type) {
+	case int64:
+		return time.Duration(v) * time.Second, nil
+	case string:
+		v = strings.TrimSpace(v)
+		if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Duration(secs) * time.Second, nil
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("Configuration '%s' under [%s] heading is not a duration: '%s'.", property, app, v)
+		}
+		return d, nil
+	}
+	return 0, fmt.Errorf("Configuration '%s' under [%s] heading is not a duration.", property, app)
+}
+
+// sizeUnits maps size suffixes to their multiplier in bytes.
+var sizeUnits = map[string]int64{
+	"B":   1,
+	"KB":  1000,
+	"MB":  1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"TB":  1000 * 1000 * 1000 * 1000,
+	"KIB": 1 << 10,
+	"MIB": 1 << 20,
+	"GIB": 1 << 30,
+	"TIB": 1 << 40,
+}
+
+// GetAppPropertySize gets the property for app as a number of bytes.  Values
+// may carry a decimal (KB, MB, GB, TB) or binary (KiB, MiB, GiB, TiB) suffix,
+// e.g. "256MB"; plain integers are taken as bytes.
+func (ms *ManagerService) GetAppPropertySize(app string, property string) (int64, error) {
+	value, ok := ms.lookupAppProperty(app, property)
+	if !ok {
+		return 0, fmt.Errorf("Configuration missing '%s' section under [%s] heading.", property, app)
+	}
+
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case string:
+		size, err := parseSize(v)
+		if err != nil {
+			return 0, fmt.Errorf("Configuration '%s' under [%s] heading is not a size: %s", property, app, err)
+		}
+		return size, nil
+	}
+	return 0, fmt.Errorf("Configuration '%s' under [%s] heading is not a size.", property, app)
+}
+
+// parseSize parses a human readable size such as "10MB" or "512KiB" into bytes,
+// rejecting sizes which overflow an int64.
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i == 0 {
+		return 0, fmt.Errorf("'%s' has no number", s)
+	}
+
+	number, unit := s, "B"
+	if i > 0 {
+		number, unit = s[:i], strings.ToUpper(strings.TrimSpace(s[i:]))
+	}
+
+	multiplier, ok := sizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("'%s' has unknown unit '%s'", s, s[i:])
+	}
+
+	if n, err := strconv.ParseInt(number, 10, 64); err == nil {
+		if n > math.MaxInt64/multiplier {
+			return 0, fmt.Errorf("'%s' is too large", s)
+		}
+		return n * multiplier, nil
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a number", number)
+	}
+	// float64(math.MaxInt64) rounds up to 2^63, which int64 cannot hold
+	bytes := f * float64(multiplier)
+	if bytes >= float64(math.MaxInt64) {
+		return 0, fmt.Errorf("'%s' is too large", s)
+	}
+	return int64(bytes), nil
+}
+
+// GetAppPropertyStrings gets the property for app as a slice of strings.  A
+// TOML array of strings is returned as is, while a string value (e.g. from an
+// environment override) is split on commas.
+func (ms *ManagerService) GetAppPropertyStrings(app string, property string) ([]string, error) {
+	value, ok := ms.lookupAppProperty(app, property)
+	if !ok {
+		return nil, fmt.Errorf("Configuration missing '%s' section under [%s] heading.", property, app)
+	}
+
+	switch v := value.(type) {
+	case string:
+		parts := []string{}
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				parts = append(parts, p)
+			}
+		}
+		return parts, nil
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("Configuration '%s' under [%s] heading must contain only strings.", property, app)
+			}
+			parts = append(parts, s)
+		}
+		return parts, nil
+	}
+	return nil, fmt.Errorf("Configuration '%s' under [%s] heading is not an array of strings.", property, app)
+}
+
+// GetAppTables gets the array of tables defined by [[app.property]] headings,
+// returning an error if the key is absent or is not an array of tables.
+func (ms *ManagerService) GetAppTables(app string, property string) ([]*toml.Tree, error) {
+	value, ok := ms.Get(app + "." + property)
+	if !ok {
+		return nil, fmt.Errorf("Configuration missing '%s' section under [%s] heading.", property, app)
+	}
+
+	tables, ok := value.([]*toml.Tree)
+	if !ok {
+		return nil, fmt.Errorf("Configuration '%s' under [%s] heading is not an array of tables.", property, app)
+	}
+	return tables, nil
+}
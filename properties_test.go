@@ -0,0 +1,149 @@
+package governor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestManager returns a manager loaded from the TOML config text.
+func newTestManager(t *testing.T, config string) *ManagerService {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ms := &ManagerService{}
+	if err := ms.InitManager(path); err != nil {
+		t.Fatalf("InitManager: %s", err)
+	}
+	return ms
+}
+
+// propertyCase is a value for testapp's "value" property, from the TOML
+// config when toml is set and from TESTAPP_VALUE when env is set.
+type propertyCase struct {
+	name    string
+	toml    string
+	env     string
+	want    interface{}
+	wantErr bool
+}
+
+func (c propertyCase) manager(t *testing.T) *ManagerService {
+	config := "[testapp]\n"
+	if c.toml != "" {
+		config += "value = " + c.toml + "\n"
+	}
+	if c.env != "" {
+		t.Setenv("TESTAPP_VALUE", c.env)
+	}
+	return newTestManager(t, config)
+}
+
+func runPropertyCases(t *testing.T, cases []propertyCase, get func(ms *ManagerService) (interface{}, error)) {
+	t.Helper()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := get(c.manager(t))
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %#v, want %#v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestGetAppPropertyInt(t *testing.T) {
+	runPropertyCases(t, []propertyCase{
+		{name: "toml", toml: "42", want: 42},
+		{name: "toml string", toml: `"42"`, want: 42},
+		{name: "env", env: "42", want: 42},
+		{name: "env over toml", toml: "1", env: " 42 ", want: 42},
+		{name: "missing", wantErr: true},
+		{name: "bad toml", toml: `"forty"`, wantErr: true},
+		{name: "bad toml type", toml: "true", wantErr: true},
+		{name: "bad env", toml: "1", env: "forty", wantErr: true},
+	}, func(ms *ManagerService) (interface{}, error) {
+		return ms.GetAppPropertyInt("testapp", "value")
+	})
+}
+
+func TestGetAppPropertyBool(t *testing.T) {
+	runPropertyCases(t, []propertyCase{
+		{name: "toml", toml: "true", want: true},
+		{name: "toml string", toml: `"false"`, want: false},
+		{name: "env", env: "1", want: true},
+		{name: "env over toml", toml: "true", env: "false", want: false},
+		{name: "missing", wantErr: true},
+		{name: "bad toml", toml: `"maybe"`, wantErr: true},
+		{name: "bad toml type", toml: "1", wantErr: true},
+		{name: "bad env", toml: "true", env: "maybe", wantErr: true},
+	}, func(ms *ManagerService) (interface{}, error) {
+		return ms.GetAppPropertyBool("testapp", "value")
+	})
+}
+
+func TestGetAppPropertyDuration(t *testing.T) {
+	runPropertyCases(t, []propertyCase{
+		{name: "toml", toml: `"1m30s"`, want: 90 * time.Second},
+		{name: "toml seconds", toml: "5", want: 5 * time.Second},
+		{name: "env", env: "250ms", want: 250 * time.Millisecond},
+		{name: "env seconds", env: "5", want: 5 * time.Second},
+		{name: "env over toml", toml: `"1s"`, env: "2s", want: 2 * time.Second},
+		{name: "missing", wantErr: true},
+		{name: "bad toml", toml: `"soon"`, wantErr: true},
+		{name: "bad toml type", toml: "true", wantErr: true},
+		{name: "bad env", toml: `"1s"`, env: "soon", wantErr: true},
+	}, func(ms *ManagerService) (interface{}, error) {
+		return ms.GetAppPropertyDuration("testapp", "value")
+	})
+}
+
+func TestGetAppPropertySize(t *testing.T) {
+	runPropertyCases(t, []propertyCase{
+		{name: "toml", toml: `"10MB"`, want: int64(10 * 1000 * 1000)},
+		{name: "toml binary", toml: `"512KiB"`, want: int64(512 << 10)},
+		{name: "toml bytes", toml: "1024", want: int64(1024)},
+		{name: "toml fraction", toml: `"1.5KB"`, want: int64(1500)},
+		{name: "env", env: "2GiB", want: int64(2 << 30)},
+		{name: "env over toml", toml: `"1MB"`, env: "2MB", want: int64(2 * 1000 * 1000)},
+		{name: "missing", wantErr: true},
+		{name: "bad toml unit", toml: `"10XB"`, wantErr: true},
+		{name: "bad toml number", toml: `"MB"`, wantErr: true},
+		{name: "bad toml type", toml: "true", wantErr: true},
+		{name: "bad env", toml: `"1MB"`, env: "lots", wantErr: true},
+		{name: "largest", toml: `"8388607TiB"`, want: int64(8388607 << 40)},
+		{name: "overflow", toml: `"9999999TB"`, wantErr: true},
+		{name: "fraction overflow", toml: `"99999999.5TB"`, wantErr: true},
+		{name: "integer overflow", toml: `"99999999999999999999"`, wantErr: true},
+		{name: "env overflow", env: "9223372036854775807KB", wantErr: true},
+	}, func(ms *ManagerService) (interface{}, error) {
+		return ms.GetAppPropertySize("testapp", "value")
+	})
+}
+
+func TestGetAppPropertyStrings(t *testing.T) {
+	runPropertyCases(t, []propertyCase{
+		{name: "toml", toml: `["a", "b"]`, want: []string{"a", "b"}},
+		{name: "toml string", toml: `"a, b"`, want: []string{"a", "b"}},
+		{name: "env", env: "a,b,,c", want: []string{"a", "b", "c"}},
+		{name: "env over toml", toml: `["a"]`, env: "b", want: []string{"b"}},
+		{name: "missing", wantErr: true},
+		{name: "bad toml element", toml: `["a", 1]`, wantErr: true},
+		{name: "bad toml type", toml: "1", wantErr: true},
+	}, func(ms *ManagerService) (interface{}, error) {
+		return ms.GetAppPropertyStrings("testapp", "value")
+	})
+}
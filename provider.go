@@ -0,0 +1,229 @@
+package governor
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// ConfigProvider supplies the manager's configuration, so it need not come
+// from a TOML file.
+type ConfigProvider interface {
+	// Load returns the configuration, whose top-level tables are app
+	// sections, and the sources it was read from, such as file paths.  It is called again
+	// on each reload.
+	Load() (map[string]interface{}, []string, error)
+}
+
+// treeProvider is implemented by providers which load a tree directly, so
+// the manager can use it without a round trip through a map, which cannot
+// represent arrays mixing value types.
+type treeProvider interface {
+	loadTree() (*toml.Tree, []string, error)
+}
+
+// boundProvider is implemented by providers which read files, so that once
+// given to a manager they honour its read timeout and log through it.
+type boundProvider interface {
+	bind(ms *ManagerService) ConfigProvider
+}
+
+// NewFileProvider returns a provider reading path as format: "toml", "yaml"
+// or "json", or from path's extension when format is empty.  TOML files may
+// include others, as with InitManager.  Warnings, such as a duplicated
+// section, are logged through the manager the provider is given to.
+func NewFileProvider(path string, format string) ConfigProvider {
+	if format == "" {
+		format = formatForPath(path)
+	}
+	return &fileProvider{path: path, format: format}
+}
+
+type fileProvider struct {
+	path   string
+	format string
+	ms     *ManagerService
+}
+
+func (p *fileProvider) Load() (map[string]interface{}, []string, error) {
+	tree, sources, err := p.loadTree()
+	if err != nil {
+		return nil, nil, err
+	}
+	return tree.ToMap(), sources, nil
+}
+
+func (p *fileProvider) loadTree() (*toml.Tree, []string, error) {
+	// until given to a manager there is no log to warn through, so warnings
+	// are dropped; the manager reports them when it loads the provider
+	opts := loadOptions{timeout: defaultReadTimeout, warnf: func(string, ...interface{}) {}}
+	if p.ms != nil {
+		opts = p.ms.loadOptions()
+	}
+	tree, sources, err := loadConfigFormat(p.path, p.format, opts)
+
+	// the bounded read reports a missing file, rather than a separate stat
+	// which could hang on a wedged filesystem
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) && pathErr.Path == p.path && errors.Is(pathErr, fs.ErrNotExist) {
+		return nil, nil, fmt.Errorf("File '%s' does not exist.", p.path)
+	}
+	return tree, sources, err
+}
+
+func (p *fileProvider) bind(ms *ManagerService) ConfigProvider {
+	return &fileProvider{path: p.path, format: p.format, ms: ms}
+}
+
+// NewEnvProvider returns a provider building a section for each of apps
+// from the environment alone, following the APPNAME_PROPERTY convention:
+// EXAMPLE_APP_DBSERVER=db sets dbserver = "db" under [example_app].  Values
+// are strings, which the typed getters convert.  Each app with any variables
+// set is reported as a source such as "env:EXAMPLE_APP_".
+func NewEnvProvider(apps ...string) ConfigProvider {
+	return envProvider(apps)
+}
+
+type envProvider []string
+
+func (apps envProvider) Load() (map[string]interface{}, []string, error) {
+	config := map[string]interface{}{}
+	sources := []string{}
+	environ := os.Environ()
+	for _, app := range apps {
+		prefix := envKey(app, "")
+		section := map[string]interface{}{}
+		for _, kv := range environ {
+			i := strings.Index(kv, "=")
+			if i < 0 || !strings.HasPrefix(kv[:i], prefix) || len(kv[:i]) == len(prefix) {
+				continue
+			}
+			section[strings.ToLower(kv[len(prefix):i])] = kv[i+1:]
+		}
+		config[app] = section
+		if len(section) > 0 {
+			sources = append(sources, "env:"+prefix)
+		}
+	}
+	return config, sources, nil
+}
+
+// NewLayeredProvider returns a provider merging the configuration of each
+// of providers, with later ones overriding earlier ones key by key, e.g. a
+// YAML file overridden by the environment:
+//
+//	governor.NewLayeredProvider(
+//		governor.NewFileProvider("config.yaml", ""),
+//		governor.NewEnvProvider("example_app"),
+//	)
+func NewLayeredProvider(providers ...ConfigProvider) ConfigProvider {
+	return layeredProvider(providers)
+}
+
+type layeredProvider []ConfigProvider
+
+func (layers layeredProvider) Load() (map[string]interface{}, []string, error) {
+	config := map[string]interface{}{}
+	sources := []string{}
+	for _, p := range layers {
+		m, s, err := p.Load()
+		if err != nil {
+			return nil, nil, err
+		}
+		mergeMaps(config, m)
+		sources = append(sources, s...)
+	}
+	return config, sources, nil
+}
+
+func (layers layeredProvider) bind(ms *ManagerService) ConfigProvider {
+	bound := make(layeredProvider, len(layers))
+	for i, p := range layers {
+		if b, ok := p.(boundProvider); ok {
+			p = b.bind(ms)
+		}
+		bound[i] = p
+	}
+	return bound
+}
+
+// mergeMaps copies src into dst, descending into tables both define.
+func mergeMaps(dst map[string]interface{}, src map[string]interface{}) {
+	for k, v := range src {
+		sub, ok := v.(map[string]interface{})
+		existing, both := dst[k].(map[string]interface{})
+		if ok && both {
+			mergeMaps(existing, sub)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// InitManagerProvider loads the manager's configuration from p, which is
+// also used for reloads, prepares the datastore config and checks for
+// unresolved placeholders.  Datastores initialized before are closed once p
+// loads, and must be initialized again.
+func (ms *ManagerService) InitManagerProvider(p ConfigProvider) error {
+	if b, ok := p.(boundProvider); ok {
+		p = b.bind(ms)
+	}
+
+	tree, sources, err := loadProvider(p)
+	if err != nil {
+		ms.emit(EventConfigLoaded, "", err)
+		return err
+	}
+
+	ms.mu.Lock()
+	ms.Config = tree
+	ms.provider = p
+	ms.sources = sources
+	ms.mu.Unlock()
+	ms.resetFeatures()
+
+	// datastores opened under the previous configuration are closed rather
+	// than dropped
+	if err := ms.Close(); err != nil {
+		ms.Log().Errorf("InitManagerProvider: %s", err)
+	}
+
+	err = ms.applyLogLevel()
+	if err == nil {
+		err = ms.checkLoadedPlaceholders()
+	}
+	ms.emit(EventConfigLoaded, "", err)
+	return err
+}
+
+// loadProvider loads p into a tree.
+func loadProvider(p ConfigProvider) (*toml.Tree, []string, error) {
+	if tp, ok := p.(treeProvider); ok {
+		return tp.loadTree()
+	}
+
+	m, sources, err := p.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	tree, err := treeFromMap(m)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to load configuration: %s", err)
+	}
+	return tree, sources, nil
+}
+
+// treeFromMap converts m to a tree, returning an error where
+// toml.TreeFromMap panics, as it does on arrays mixing value types.
+func treeFromMap(m map[string]interface{}) (tree *toml.Tree, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%v", p)
+		}
+	}()
+	return toml.TreeFromMap(m)
+}
@@ -0,0 +1,105 @@
+package governor
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileProviderMissing(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing.toml")
+
+	_, _, err := NewFileProvider(missing, "").Load()
+	if err == nil || err.Error() != "File '"+missing+"' does not exist." {
+		t.Errorf("missing file: got error %v", err)
+	}
+
+	main := filepath.Join(dir, "main.toml")
+	if err := os.WriteFile(main, []byte("include = [\"gone.toml\"]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = NewFileProvider(main, "").Load()
+	if err == nil || !strings.Contains(err.Error(), "gone.toml") || strings.Contains(err.Error(), "'"+main+"' does not exist") {
+		t.Errorf("missing include: got error %v, want one naming gone.toml", err)
+	}
+}
+
+func TestEnvProviderSources(t *testing.T) {
+	t.Setenv("SET_APP_PORT", "8080")
+	os.Unsetenv("UNSET_APP_PORT")
+
+	config, sources, err := NewEnvProvider("set_app", "unset_app").Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := config["set_app"].(map[string]interface{})["port"]; got != "8080" {
+		t.Errorf("port = %v, want 8080", got)
+	}
+	if len(sources) != 1 || sources[0] != "env:SET_APP_" {
+		t.Errorf("sources = %v, want [env:SET_APP_]", sources)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte("[set_app]\nhost = \"::1\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ms := &ManagerService{}
+	if err := ms.InitManagerProvider(NewLayeredProvider(NewFileProvider(path, ""), NewEnvProvider("set_app"))); err != nil {
+		t.Fatalf("InitManagerProvider: %s", err)
+	}
+	got := ms.ConfigSources()
+	if len(got) != 2 || !strings.HasSuffix(got[0], "config.toml") || got[1] != "env:SET_APP_" {
+		t.Errorf("ConfigSources() = %v", got)
+	}
+}
+
+func TestInitManagerClosesDatastores(t *testing.T) {
+	ms := sqliteManager(t, "one")
+	db := appDB(ms, "one")
+
+	path := filepath.Join(t.TempDir(), "next.toml")
+	if err := os.WriteFile(path, []byte("[one]\ndbdriver = \"sqlite3\"\ndbpath = \"next.db\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ms.InitManager(path); err != nil {
+		t.Fatalf("InitManager: %s", err)
+	}
+
+	if err := db.Ping(); err == nil {
+		t.Error("the previous configuration's connection is still open")
+	}
+	if _, ok := ms.DBConfig["one"]; ok {
+		t.Error("the previous configuration's datastore is still registered")
+	}
+}
+
+func TestFileProviderWarnings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte("[a]\nx = 1\n\n[a]\nx = 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdlog bytes.Buffer
+	log.SetOutput(&stdlog)
+	defer log.SetOutput(os.Stderr)
+	if _, _, err := NewFileProvider(path, "").Load(); err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+	if stdlog.Len() != 0 {
+		t.Errorf("an unbound provider logged %q", stdlog.String())
+	}
+
+	logs := &recordLogger{}
+	ms := &ManagerService{}
+	ms.SetLogger(logs)
+	if err := ms.InitManagerProvider(NewFileProvider(path, "")); err != nil {
+		t.Fatalf("InitManagerProvider: %s", err)
+	}
+	if !strings.Contains(logs.String(), "warn Section [a] is defined 2 times") {
+		t.Errorf("manager logs = %q, want the duplicate section warning", logs.String())
+	}
+}
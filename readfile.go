@@ -0,0 +1,56 @@
+package governor
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// defaultReadTimeout bounds each config file operation unless overridden.
+const defaultReadTimeout = 30 * time.Second
+
+// SetReadTimeout bounds how long reading each config file may take, so a
+// hung network filesystem fails startup instead of wedging it.  A zero or
+// negative d restores the default, which the GOVERNOR_READ_TIMEOUT
+// environment variable (e.g. "5s") also sets.
+func (ms *ManagerService) SetReadTimeout(d time.Duration) {
+	atomic.StoreInt64(&ms.readTimeout, int64(d))
+}
+
+// fileTimeout returns the timeout for config file operations.
+func (ms *ManagerService) fileTimeout() time.Duration {
+	if d := time.Duration(atomic.LoadInt64(&ms.readTimeout)); d > 0 {
+		return d
+	}
+	if v, ok := os.LookupEnv("GOVERNOR_READ_TIMEOUT"); ok {
+		d, err := time.ParseDuration(v)
+		if err == nil && d > 0 {
+			return d
+		}
+		ms.Log().Warnf("GOVERNOR_READ_TIMEOUT '%s' is not a positive duration, using %s.", v, defaultReadTimeout)
+	}
+	return defaultReadTimeout
+}
+
+// readFile reads path into memory, giving up after timeout.  A read blocked
+// in the kernel cannot be interrupted, so it is left to finish in the
+// background.
+func readFile(path string, timeout time.Duration) ([]byte, error) {
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		data, err := os.ReadFile(path)
+		done <- result{data, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.data, r.err
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("Timed out after %s reading '%s'.", timeout, path)
+	}
+}
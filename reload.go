@@ -0,0 +1,213 @@
+package governor
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ConfigChange describes a configuration reload.  Keys are the dotted keys
+// which were added, removed or changed, Apps the top-level sections they
+// belong to, and Datastores the apps whose datastores were re-initialized.
+// Err is set when any of those datastores failed to reconnect; they keep
+// their previous connection.
+type ConfigChange struct {
+	Keys       []string
+	Apps       []string
+	Datastores []string
+	Err        error
+}
+
+// OnConfigChange subscribes fn to configuration reloads which changed at
+// least one key.  Subscribers are called synchronously, after datastores
+// have been re-initialized, in the order they subscribed.
+func (ms *ManagerService) OnConfigChange(fn func(ConfigChange)) {
+	ms.eventsMu.Lock()
+	defer ms.eventsMu.Unlock()
+
+	ms.configSubscribers = append(ms.configSubscribers, fn)
+}
+
+// Reload reloads the configuration given to InitManager or
+// InitManagerProvider and re-initializes only the datastores whose
+// connection settings changed, along with those of newly added apps which
+// configure a dbdriver.  The datastores of removed apps are closed.  The
+// change is then delivered to OnConfigChange subscribers.  An error loading
+// the configuration leaves the previous one in place.
+func (ms *ManagerService) Reload() (ConfigChange, error) {
+	keys, err := ms.ReloadConfigDiff()
+	if err != nil {
+		return ConfigChange{}, fmt.Errorf("Reload: %s", err)
+	}
+
+	change := ConfigChange{Keys: keys, Apps: changedApps(keys)}
+	if len(keys) == 0 {
+		return change, nil
+	}
+
+	present := map[string]bool{}
+	for _, app := range ms.Apps() {
+		present[app] = true
+	}
+
+	failed := []string{}
+	for _, app := range change.Apps {
+		if !ms.datastoreChanged(app, keys) {
+			continue
+		}
+		if !present[app] {
+			// the section was removed, so there is nothing to reconnect to
+			if err := ms.CloseDatastore(app); err != nil {
+				ms.AppLog(app).Warnf("Reload: closing removed [%s] datastore: %s", app, err)
+			}
+			continue
+		}
+		if err := ms.ReinitDatastore(app); err != nil {
+			ms.AppLog(app).Errorf("Reload: [%s] %s", app, err)
+			failed = append(failed, err.Error())
+			continue
+		}
+		change.Datastores = append(change.Datastores, app)
+	}
+	if len(failed) > 0 {
+		change.Err = fmt.Errorf("Reload: %d datastore(s) failed: %s", len(failed), strings.Join(failed, "; "))
+	}
+	ms.Log().Infof("Reload: %d key(s) changed, %d datastore(s) re-initialized.", len(keys), len(change.Datastores))
+
+	ms.eventsMu.RLock()
+	subscribers := make([]func(ConfigChange), len(ms.configSubscribers))
+	copy(subscribers, ms.configSubscribers)
+	ms.eventsMu.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(change)
+	}
+	return change, change.Err
+}
+
+// datastoreChanged reports whether keys include a datastore setting for app
+// which should be reconnected: one already initialized, or a new one.
+func (ms *ManagerService) datastoreChanged(app string, keys []string) bool {
+	changed := false
+	for _, key := range keys {
+		if strings.HasPrefix(key, app+".") && isDatastoreKey(key[strings.LastIndex(key, ".")+1:]) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return false
+	}
+
+	ms.dbmu.RLock()
+	_, initialized := ms.DBConfig[app]
+	if _, ok := ms.stores[app]; ok {
+		initialized = true
+	}
+	ms.dbmu.RUnlock()
+	if initialized {
+		return true
+	}
+
+	_, err := ms.GetAppProperty(app, "dbdriver")
+	return err == nil
+}
+
+// isDatastoreKey reports whether property is read when connecting an app's
+// datastore: dbdriver, dburl, database and the other db settings, along
+// with their _file and _cmd variants.
+func isDatastoreKey(property string) bool {
+	return strings.HasPrefix(property, "db") || strings.HasPrefix(property, "database")
+}
+
+// changedApps returns the sorted top-level sections of the dotted keys.
+func changedApps(keys []string) []string {
+	seen := map[string]bool{}
+	apps := []string{}
+	for _, key := range keys {
+		i := strings.Index(key, ".")
+		if i < 0 || seen[key[:i]] {
+			continue
+		}
+		seen[key[:i]] = true
+		apps = append(apps, key[:i])
+	}
+	sort.Strings(apps)
+	return apps
+}
+
+// WatchConfig reloads the configuration, as Reload does, whenever the
+// process receives SIGHUP and, with a positive interval, whenever polling
+// finds one of the loaded config files has been modified.  It watches until
+// the returned function is called or the manager shuts down.
+func (ms *ManagerService) WatchConfig(interval time.Duration) (stop func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	done := make(chan struct{})
+	var once sync.Once
+	stop = func() { once.Do(func() { close(done) }) }
+
+	modified := ms.configModTimes()
+	go func() {
+		defer signal.Stop(hup)
+
+		var tick <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for {
+			select {
+			case <-hup:
+				ms.Log().Infof("WatchConfig: received SIGHUP, reloading configuration.")
+			case <-tick:
+				if current := ms.configModTimes(); sameModTimes(modified, current) {
+					continue
+				}
+				ms.Log().Infof("WatchConfig: configuration file modified, reloading.")
+			case <-done:
+				return
+			case <-ms.Done():
+				return
+			}
+			if _, err := ms.Reload(); err != nil {
+				ms.Log().Errorf("WatchConfig: %s", err)
+			}
+			modified = ms.configModTimes()
+		}
+	}()
+	return stop
+}
+
+// configModTimes returns the modification time of each loaded config file.
+// Files which cannot be read are left out, so they count as modified once
+// they reappear.
+func (ms *ManagerService) configModTimes() map[string]time.Time {
+	times := map[string]time.Time{}
+	for _, path := range ms.ConfigSources() {
+		if fi, err := os.Stat(path); err == nil {
+			times[path] = fi.ModTime()
+		}
+	}
+	return times
+}
+
+func sameModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, t := range a {
+		if !t.Equal(b[path]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package governor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// reloadManager is a manager loaded from a config file which write
+// replaces.
+func reloadManager(t *testing.T, config string) (ms *ManagerService, write func(string)) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	write = func(config string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(config)
+
+	ms = &ManagerService{}
+	if err := ms.InitManager(path); err != nil {
+		t.Fatalf("InitManager: %s", err)
+	}
+	t.Cleanup(func() { ms.Close() })
+	return ms, write
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	db := func(name string) string { return filepath.Join(dir, name+".db") }
+	ms, write := reloadManager(t, fmt.Sprintf(`[moved]
+dbdriver = "sqlite3"
+dbpath = %q
+
+[kept]
+dbdriver = "sqlite3"
+dbpath = %q
+port = 8080
+
+[gone]
+dbdriver = "sqlite3"
+dbpath = %q
+`, db("moved"), db("kept"), db("gone")))
+	if err := ms.InitAllDatastores(true); err != nil {
+		t.Fatalf("InitAllDatastores: %s", err)
+	}
+	moved, kept, gone := appDB(ms, "moved"), appDB(ms, "kept"), appDB(ms, "gone")
+
+	changes := []ConfigChange{}
+	ms.OnConfigChange(func(c ConfigChange) { changes = append(changes, c) })
+
+	write(fmt.Sprintf(`[moved]
+dbdriver = "sqlite3"
+dbpath = %q
+
+[kept]
+dbdriver = "sqlite3"
+dbpath = %q
+port = 8081
+
+[added]
+dbdriver = "sqlite3"
+dbpath = %q
+`, db("moved2"), db("kept"), db("added")))
+	change, err := ms.Reload()
+	if err != nil {
+		t.Fatalf("Reload: %s", err)
+	}
+
+	want := ConfigChange{
+		Keys:       []string{"added.dbdriver", "added.dbpath", "gone.dbdriver", "gone.dbpath", "kept.port", "moved.dbpath"},
+		Apps:       []string{"added", "gone", "kept", "moved"},
+		Datastores: []string{"added", "moved"},
+	}
+	if !reflect.DeepEqual(change, want) {
+		t.Errorf("change = %+v, want %+v", change, want)
+	}
+	if len(changes) != 1 || !reflect.DeepEqual(changes[0], want) {
+		t.Errorf("subscribers got %+v, want the change once", changes)
+	}
+
+	if appDB(ms, "moved") == moved || moved.Ping() == nil {
+		t.Error("[moved] kept its previous connection")
+	}
+	if appDB(ms, "kept") != kept || kept.Ping() != nil {
+		t.Error("[kept] was reconnected though its datastore settings did not change")
+	}
+	if _, err := ms.DB("gone"); err == nil || gone.Ping() == nil {
+		t.Error("[gone] datastore left open after its section was removed")
+	}
+	if _, err := ms.DB("added"); err != nil {
+		t.Errorf("[added] datastore: %s", err)
+	}
+	if got, _ := ms.GetAppPropertyInt("kept", "port"); got != 8081 {
+		t.Errorf("kept.port = %d, want the reloaded 8081", got)
+	}
+}
+
+func TestReloadUnchanged(t *testing.T) {
+	ms, _ := reloadManager(t, "[testapp]\nport = 8080\n")
+	called := false
+	ms.OnConfigChange(func(ConfigChange) { called = true })
+
+	change, err := ms.Reload()
+	if err != nil {
+		t.Fatalf("Reload: %s", err)
+	}
+	if len(change.Keys) != 0 || called {
+		t.Errorf("change = %+v, subscriber called %v; want nothing for an unchanged file", change, called)
+	}
+}
+
+func TestReloadInvalidKeepsConfig(t *testing.T) {
+	ms, write := reloadManager(t, "[testapp]\nport = 8080\n")
+	called := false
+	ms.OnConfigChange(func(ConfigChange) { called = true })
+
+	write("[testapp\nport = 8081\n")
+	if _, err := ms.Reload(); err == nil || !strings.HasPrefix(err.Error(), "Reload: ") {
+		t.Fatalf("got error %v, want the parse error", err)
+	}
+	if got, _ := ms.GetAppPropertyInt("testapp", "port"); got != 8080 {
+		t.Errorf("port = %d, want the previous 8080", got)
+	}
+	if called {
+		t.Error("subscriber called for a failed reload")
+	}
+}
+
+func TestWatchConfigPolls(t *testing.T) {
+	ms, write := reloadManager(t, "[testapp]\nport = 8080\n")
+	changes := make(chan ConfigChange, 1)
+	ms.OnConfigChange(func(c ConfigChange) { changes <- c })
+
+	stop := ms.WatchConfig(10 * time.Millisecond)
+	defer stop()
+
+	write("[testapp]\nport = 8081\n")
+	// file systems with coarse timestamps may not see the write as a change
+	source := ms.ConfigSources()[0]
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(source, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case c := <-changes:
+		if !reflect.DeepEqual(c.Keys, []string{"testapp.port"}) {
+			t.Errorf("keys = %v, want [testapp.port]", c.Keys)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WatchConfig did not reload the modified file")
+	}
+
+	stop()
+	write("[testapp]\nport = 8082\n")
+	later := future.Add(time.Hour)
+	os.Chtimes(source, later, later)
+	select {
+	case c := <-changes:
+		t.Errorf("reloaded %v after stop", c.Keys)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !windows
+// +build !windows
+
+package governor
+
+import (
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWatchConfigSIGHUP(t *testing.T) {
+	ms, write := reloadManager(t, "[testapp]\nport = 8080\n")
+	changes := make(chan ConfigChange, 1)
+	ms.OnConfigChange(func(c ConfigChange) { changes <- c })
+
+	// without polling only SIGHUP triggers a reload
+	stop := ms.WatchConfig(0)
+	defer stop()
+
+	write("[testapp]\nport = 8081\n")
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case c := <-changes:
+		if !reflect.DeepEqual(c.Keys, []string{"testapp.port"}) {
+			t.Errorf("keys = %v, want [testapp.port]", c.Keys)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WatchConfig did not reload on SIGHUP")
+	}
+}
@@ -0,0 +1,77 @@
+package governor
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrorResponse is the body Respond writes when data is an error.
+type ErrorResponse struct {
+	XMLName xml.Name `json:"-" xml:"error"`
+	Error   string   `json:"error" xml:"message"`
+}
+
+// Respond writes data with status, encoded as XML when the request's Accept
+// header prefers application/xml or text/xml and as JSON otherwise.  An error
+// is written as an ErrorResponse.
+func Respond(w http.ResponseWriter, r *http.Request, status int, data interface{}) error {
+	if err, ok := data.(error); ok {
+		data = ErrorResponse{Error: err.Error()}
+	}
+
+	if prefersXML(r.Header.Get("Accept")) {
+		body, err := xml.Marshal(data)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.WriteHeader(status)
+		_, err = w.Write(append([]byte(xml.Header), body...))
+		return err
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	return err
+}
+
+// prefersXML reports whether accept ranks an XML media type above JSON.
+// Wildcards and unsupported types fall back to JSON.
+func prefersXML(accept string) bool {
+	jsonQ, xmlQ := -1.0, -1.0
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+
+		switch mediaType {
+		case "application/json", "*/*", "application/*":
+			if q > jsonQ {
+				jsonQ = q
+			}
+		case "application/xml", "text/xml":
+			if q > xmlQ {
+				xmlQ = q
+			}
+		}
+	}
+
+	return xmlQ > 0 && xmlQ > jsonQ
+}
@@ -0,0 +1,29 @@
+//go:build windows
+// +build windows
+
+package governor
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// restartSignal returns nil: graceful restart relies on SIGUSR2 and file
+// descriptor inheritance, which this platform lacks.
+func (ms *ManagerService) restartSignal(apis []*API) (<-chan os.Signal, func()) {
+	for _, api := range apis {
+		if api.gracefulRestart {
+			ms.Log().Warnf("Daemonize: [%s] graceful_restart is not supported on this platform.", api.app)
+		}
+	}
+	return nil, func() {}
+}
+
+func handoff(apis ...*API) error {
+	return errors.New("graceful restart is not supported on this platform")
+}
+
+func inheritedListener(app string) (net.Listener, error) {
+	return nil, nil
+}
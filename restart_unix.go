@@ -0,0 +1,151 @@
+//go:build !windows
+// +build !windows
+
+package governor
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// listenerFDsEnv names the environment variable carrying the inherited
+// listeners to a restarted process, as comma-separated app=fd pairs.
+const listenerFDsEnv = "GOVERNOR_LISTENER_FDS"
+
+// the listeners inherited from the parent process, by app
+var (
+	inheritedOnce sync.Once
+	inheritedMu   sync.Mutex
+	inheritedFDs  map[string]int
+	inheritedErr  error
+)
+
+// restartSignal returns a channel receiving SIGUSR2 when graceful_restart is
+// enabled for any of apis, or nil otherwise.  The one handler serves every
+// API the manager runs, so a restart hands all of them over together.
+func (ms *ManagerService) restartSignal(apis []*API) (<-chan os.Signal, func()) {
+	for _, api := range apis {
+		if api.gracefulRestart {
+			sig := make(chan os.Signal, 1)
+			signal.Notify(sig, syscall.SIGUSR2)
+			return sig, func() { signal.Stop(sig) }
+		}
+	}
+	return nil, func() {}
+}
+
+// handoff starts a copy of the running binary which inherits the listening
+// sockets of apis, along with those of their HTTP redirect servers, so it
+// can begin accepting connections while this process drains.
+func handoff(apis ...*API) error {
+	files := []*os.File{}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	pairs := []string{}
+	pass := func(name string, l net.Listener) error {
+		tl, ok := l.(*net.TCPListener)
+		if !ok {
+			return fmt.Errorf("[%s] listener is not a TCP listener", name)
+		}
+		f, err := tl.File()
+		if err != nil {
+			return fmt.Errorf("[%s] %s", name, err)
+		}
+		files = append(files, f)
+		// ExtraFiles start at descriptor 3 in the child.
+		pairs = append(pairs, fmt.Sprintf("%s=%d", name, 2+len(files)))
+		return nil
+	}
+	for _, api := range apis {
+		api.lnMu.Lock()
+		l, redirect := api.ln, api.redirectLn
+		api.lnMu.Unlock()
+
+		if err := pass(api.app, l); err != nil {
+			return err
+		}
+		if redirect != nil {
+			if err := pass(redirectListener(api.app), redirect); err != nil {
+				return err
+			}
+		}
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	env := []string{}
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, listenerFDsEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(env, listenerFDsEnv+"="+strings.Join(pairs, ","))
+
+	return cmd.Start()
+}
+
+// inheritedListener returns the listener for app handed over by a parent
+// process during a graceful restart, or nil when there is none.
+func inheritedListener(app string) (net.Listener, error) {
+	inheritedOnce.Do(func() {
+		v := os.Getenv(listenerFDsEnv)
+		os.Unsetenv(listenerFDsEnv)
+		inheritedFDs, inheritedErr = parseListenerFDs(v)
+	})
+	if inheritedErr != nil {
+		return nil, inheritedErr
+	}
+
+	inheritedMu.Lock()
+	fd, ok := inheritedFDs[app]
+	delete(inheritedFDs, app)
+	inheritedMu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+
+	f := os.NewFile(uintptr(fd), "governor-listener-"+app)
+	defer f.Close()
+
+	return net.FileListener(f)
+}
+
+// parseListenerFDs parses the app=fd pairs of listenerFDsEnv.
+func parseListenerFDs(v string) (map[string]int, error) {
+	fds := map[string]int{}
+	if v == "" {
+		return fds, nil
+	}
+	for _, pair := range strings.Split(v, ",") {
+		i := strings.LastIndex(pair, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("invalid %s '%s'", listenerFDsEnv, v)
+		}
+		fd, err := strconv.Atoi(pair[i+1:])
+		if err != nil || fd < 3 {
+			return nil, fmt.Errorf("invalid %s '%s'", listenerFDsEnv, v)
+		}
+		fds[pair[:i]] = fd
+	}
+	return fds, nil
+}
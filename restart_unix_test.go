@@ -0,0 +1,157 @@
+//go:build !windows
+// +build !windows
+
+package governor
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseListenerFDs(t *testing.T) {
+	cases := []struct {
+		value   string
+		want    map[string]int
+		wantErr bool
+	}{
+		{value: "", want: map[string]int{}},
+		{value: "one=3", want: map[string]int{"one": 3}},
+		{value: "one=3,two=4", want: map[string]int{"one": 3, "two": 4}},
+		{value: "3", wantErr: true},
+		{value: "=3", wantErr: true},
+		{value: "one=x", wantErr: true},
+		{value: "one=1", wantErr: true},
+	}
+	for _, c := range cases {
+		got, err := parseListenerFDs(c.value)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%q: got %v, want an error", c.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", c.value, err)
+		} else if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: got %v, want %v", c.value, got, c.want)
+		}
+	}
+}
+
+func TestInheritedListenerByApp(t *testing.T) {
+	listeners := map[string]*net.TCPListener{}
+	pairs := ""
+	for _, app := range []string{"one", "two"} {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		f, err := l.(*net.TCPListener).File()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// inheritedListener takes ownership of the descriptor
+		fd, err := syscall.Dup(int(f.Fd()))
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		listeners[app] = l.(*net.TCPListener)
+		if pairs != "" {
+			pairs += ","
+		}
+		pairs += fmt.Sprintf("%s=%d", app, fd)
+	}
+
+	t.Setenv(listenerFDsEnv, pairs)
+	inheritedOnce = sync.Once{}
+	defer func() { inheritedOnce = sync.Once{} }()
+
+	for _, app := range []string{"two", "one"} {
+		l, err := inheritedListener(app)
+		if err != nil {
+			t.Fatalf("[%s] %s", app, err)
+		}
+		if l == nil {
+			t.Fatalf("[%s] no listener inherited", app)
+		}
+		if got, want := l.Addr().String(), listeners[app].Addr().String(); got != want {
+			t.Errorf("[%s] inherited %s, want %s", app, got, want)
+		}
+		l.Close()
+
+		if again, _ := inheritedListener(app); again != nil {
+			t.Errorf("[%s] listener inherited twice", app)
+		}
+	}
+	if l, _ := inheritedListener("three"); l != nil {
+		t.Errorf("inherited a listener for an app not handed over")
+	}
+}
+
+func TestRedirectListenerInherited(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd, err := syscall.Dup(int(f.Fd()))
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(listenerFDsEnv, fmt.Sprintf("testapp:redirect=%d", fd))
+	inheritedOnce = sync.Once{}
+	defer func() { inheritedOnce = sync.Once{} }()
+
+	// binding the address again would fail, as it is still in use
+	api := newTestAPI()
+	api.app = "testapp"
+	api.Address = "127.0.0.1:8443"
+	api.redirectAddr = l.Addr().String()
+	srv := api.newRedirectServer()
+	served := make(chan error, 1)
+	go func() { served <- api.listenRedirect(srv) }()
+	defer srv.Close()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := client.Get("http://" + l.Addr().String() + "/x")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusMovedPermanently {
+				t.Errorf("got %d, want %d", resp.StatusCode, http.StatusMovedPermanently)
+			}
+			break
+		}
+		select {
+		case err := <-served:
+			t.Fatalf("redirect server stopped: %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("redirect server not serving: %s", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	api.lnMu.Lock()
+	redirect := api.redirectLn
+	api.lnMu.Unlock()
+	if redirect == nil || redirect.Addr().String() != l.Addr().String() {
+		t.Errorf("redirect listener %v, want the inherited %s", redirect, l.Addr())
+	}
+}
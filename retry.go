@@ -0,0 +1,165 @@
+package governor
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"math/rand"
+	"net"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// defaultRetryAttempts is used when db_retry_attempts is not configured.
+const defaultRetryAttempts = 3
+
+// retryBackoff is the delay before the first retry, doubled for each one
+// after.
+const retryBackoff = 100 * time.Millisecond
+
+// transientSQLStates are SQLSTATE codes (postgres and the standard) which
+// indicate a retryable failure: serialization failure, deadlock, too many
+// connections, admin or crash shutdown and cannot connect now.
+var transientSQLStates = map[string]bool{
+	"40001": true,
+	"40P01": true,
+	"53300": true,
+	"57P01": true,
+	"57P02": true,
+	"57P03": true,
+}
+
+// transientMySQLErrors are mysql error numbers which indicate a retryable
+// failure: too many connections, lock wait timeout, deadlock (also mssql's
+// deadlock victim number) and server gone away or lost.
+var transientMySQLErrors = map[uint64]bool{
+	1040: true,
+	1205: true,
+	1213: true,
+	2006: true,
+	2013: true,
+}
+
+// transientMessages identify retryable errors by message where drivers
+// expose nothing better, notably sqlite's SQLITE_BUSY.
+var transientMessages = []string{
+	"database is locked",
+	"deadlock",
+	"too many connections",
+	"connection reset",
+	"connection refused",
+	"broken pipe",
+	"server closed the connection",
+	"bad connection",
+}
+
+// IsTransientDBError reports whether err is a transient database failure,
+// such as a dropped connection, too many connections, a deadlock or a
+// serialization failure, which may succeed if retried.  Constraint violations
+// and other errors in the query itself are not transient, nor are a
+// cancelled context or an expired deadline.
+func IsTransientDBError(err error) bool {
+	// context.DeadlineExceeded is also a net.Error, so check before the
+	// network errors below
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// pgx exposes SQLState(); lib/pq a Code field; go-sql-driver/mysql and
+	// go-mssqldb a Number.
+	var stater interface{ SQLState() string }
+	if errors.As(err, &stater) && transientSQLStates[stater.SQLState()] {
+		return true
+	}
+	if code, ok := errorField(err, "Code"); ok && code.Kind() == reflect.String {
+		if transientSQLStates[code.String()] || strings.HasPrefix(code.String(), "08") {
+			return true
+		}
+	}
+	if number, ok := errorField(err, "Number"); ok {
+		switch number.Kind() {
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+			if transientMySQLErrors[number.Uint()] {
+				return true
+			}
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+			if n := number.Int(); n > 0 && transientMySQLErrors[uint64(n)] {
+				return true
+			}
+		}
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, m := range transientMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// errorField returns the named field of the struct underlying err, which
+// lets driver errors be classified without importing the drivers.
+func errorField(err error, name string) (reflect.Value, bool) {
+	v := reflect.ValueOf(err)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	f := v.FieldByName(name)
+	return f, f.IsValid()
+}
+
+// WithRetry runs fn against app's datastore, retrying with exponential
+// backoff while it fails with a transient error, up to db_retry_attempts
+// attempts in total (default 3).  Retries stop early when the manager shuts
+// down.
+func (ms *ManagerService) WithRetry(app string, fn func(*gorm.DB) error) error {
+	db, err := ms.DB(app)
+	if err != nil {
+		return err
+	}
+
+	attempts := defaultRetryAttempts
+	if n, err := ms.GetAppPropertyInt(app, "db_retry_attempts"); err == nil && n > 0 {
+		attempts = n
+	}
+
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err = ms.dbError(app, fn(db))
+		if err == nil || attempt >= attempts || !IsTransientDBError(err) {
+			return err
+		}
+
+		ms.Log().Debugf("WithRetry: [%s] attempt %d failed: %s", app, attempt, err)
+		delay := backoff + time.Duration(rand.Int63n(int64(backoff)/2+1))
+		select {
+		case <-time.After(delay):
+		case <-ms.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,51 @@
+package governor
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+)
+
+// sqlStateError is a driver error exposing SQLState, as pgx's does.
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+// numberError is a driver error with a Number field, as mysql's has.
+type numberError struct {
+	Number uint16
+}
+
+func (e *numberError) Error() string { return fmt.Sprintf("error %d", e.Number) }
+
+func TestIsTransientDBError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"canceled", context.Canceled, false},
+		{"deadline exceeded", context.DeadlineExceeded, false},
+		{"wrapped deadline exceeded", fmt.Errorf("query: %w", context.DeadlineExceeded), false},
+		{"bad conn", driver.ErrBadConn, true},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{"net error", &net.OpError{Op: "dial", Err: errors.New("no route")}, true},
+		{"serialization failure", sqlStateError("40001"), true},
+		{"unique violation", sqlStateError("23505"), false},
+		{"mysql deadlock", &numberError{Number: 1213}, true},
+		{"mysql duplicate", &numberError{Number: 1062}, false},
+		{"sqlite busy", errors.New("database is locked"), true},
+		{"syntax", errors.New("syntax error at or near SELEC"), false},
+	}
+	for _, c := range cases {
+		if got := IsTransientDBError(c.err); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package governor
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// RouteInfo describes a route registered through the API.
+type RouteInfo struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// apiKey is the context key under which Handle stores the API serving a
+// route, for route middleware such as RequireAuth.
+type apiKey struct{}
+
+// Handle registers h for requests matching method and path on the API's
+// router.  Any route middleware wraps h for this route alone, the first
+// listed running first, inside the API's Use chain.
+func (api *API) Handle(method string, path string, h http.HandlerFunc, mw ...Middleware) {
+	api.handle(method, path, h, mw...)
+}
+
+// handle does the work of Handle, returning the router's route.
+func (api *API) handle(method string, path string, h http.HandlerFunc, mw ...Middleware) *mux.Route {
+	var route http.Handler = h
+	for i := len(mw) - 1; i >= 0; i-- {
+		route = mw[i](route)
+	}
+	r := api.WebService.Router.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		route.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKey{}, api)))
+	}).Methods(method)
+
+	api.routesMu.Lock()
+	api.routes = append(api.routes, RouteInfo{Method: method, Path: api.basePath + path})
+	api.routesMu.Unlock()
+	return r
+}
+
+// isStream reports whether r is routed to a streaming endpoint registered
+// with SSE, whatever its path variables.
+func (api *API) isStream(r *http.Request) bool {
+	api.routesMu.Lock()
+	streaming := len(api.streams) > 0
+	api.routesMu.Unlock()
+	if !streaming {
+		return false
+	}
+
+	var match mux.RouteMatch
+	if !api.WebService.Router.Match(r, &match) {
+		return false
+	}
+	api.routesMu.Lock()
+	defer api.routesMu.Unlock()
+	return api.streams[match.Route]
+}
+
+// Routes returns the routes registered through the API, including governor's
+// own endpoints, in registration order.  Paths include the base path.  Routes
+// added directly to the WebService's router are not tracked.
+func (api *API) Routes() []RouteInfo {
+	api.routesMu.Lock()
+	defer api.routesMu.Unlock()
+
+	return append([]RouteInfo(nil), api.routes...)
+}
+
+// GET registers h for GET requests to path, wrapped in any route middleware.
+func (api *API) GET(path string, h http.HandlerFunc, mw ...Middleware) {
+	api.Handle(http.MethodGet, path, h, mw...)
+}
+
+// HEAD registers h for HEAD requests to path, wrapped in any route middleware.
+func (api *API) HEAD(path string, h http.HandlerFunc, mw ...Middleware) {
+	api.Handle(http.MethodHead, path, h, mw...)
+}
+
+// POST registers h for POST requests to path, wrapped in any route middleware.
+func (api *API) POST(path string, h http.HandlerFunc, mw ...Middleware) {
+	api.Handle(http.MethodPost, path, h, mw...)
+}
+
+// PUT registers h for PUT requests to path, wrapped in any route middleware.
+func (api *API) PUT(path string, h http.HandlerFunc, mw ...Middleware) {
+	api.Handle(http.MethodPut, path, h, mw...)
+}
+
+// PATCH registers h for PATCH requests to path, wrapped in any route middleware.
+func (api *API) PATCH(path string, h http.HandlerFunc, mw ...Middleware) {
+	api.Handle(http.MethodPatch, path, h, mw...)
+}
+
+// DELETE registers h for DELETE requests to path, wrapped in any route middleware.
+func (api *API) DELETE(path string, h http.HandlerFunc, mw ...Middleware) {
+	api.Handle(http.MethodDelete, path, h, mw...)
+}
+
+// OPTIONS registers h for OPTIONS requests to path, wrapped in any route middleware.
+func (api *API) OPTIONS(path string, h http.HandlerFunc, mw ...Middleware) {
+	api.Handle(http.MethodOptions, path, h, mw...)
+}
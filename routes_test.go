@@ -0,0 +1,89 @@
+package governor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lakesite/ls-fibre"
+)
+
+func newTestAPI() *API {
+	return NewAPI(fibre.NewWebService("testapp", "127.0.0.1:0"), &ManagerService{})
+}
+
+// testRouteVerb registers a route with register and checks it answers
+// method but not other.
+func testRouteVerb(t *testing.T, method string, other string, register func(api *API, path string, h http.HandlerFunc)) {
+	t.Helper()
+
+	api := newTestAPI()
+	register(api, "/thing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	h := api.handler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, "/thing", nil))
+	if rec.Code != http.StatusTeapot {
+		t.Errorf("%s /thing: got %d, want %d", method, rec.Code, http.StatusTeapot)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(other, "/thing", nil))
+	if rec.Code != http.StatusMethodNotAllowed && rec.Code != http.StatusNotFound {
+		t.Errorf("%s /thing: got %d, want 405 or 404", other, rec.Code)
+	}
+
+	routes := api.Routes()
+	if len(routes) != 1 || routes[0] != (RouteInfo{Method: method, Path: "/thing"}) {
+		t.Errorf("Routes() = %v", routes)
+	}
+}
+
+func TestGET(t *testing.T) {
+	testRouteVerb(t, http.MethodGet, http.MethodPost, func(api *API, path string, h http.HandlerFunc) { api.GET(path, h) })
+}
+
+func TestHEAD(t *testing.T) {
+	testRouteVerb(t, http.MethodHead, http.MethodGet, func(api *API, path string, h http.HandlerFunc) { api.HEAD(path, h) })
+}
+
+func TestPOST(t *testing.T) {
+	testRouteVerb(t, http.MethodPost, http.MethodGet, func(api *API, path string, h http.HandlerFunc) { api.POST(path, h) })
+}
+
+func TestPUT(t *testing.T) {
+	testRouteVerb(t, http.MethodPut, http.MethodPost, func(api *API, path string, h http.HandlerFunc) { api.PUT(path, h) })
+}
+
+func TestPATCH(t *testing.T) {
+	testRouteVerb(t, http.MethodPatch, http.MethodPut, func(api *API, path string, h http.HandlerFunc) { api.PATCH(path, h) })
+}
+
+func TestDELETE(t *testing.T) {
+	testRouteVerb(t, http.MethodDelete, http.MethodGet, func(api *API, path string, h http.HandlerFunc) { api.DELETE(path, h) })
+}
+
+func TestOPTIONS(t *testing.T) {
+	testRouteVerb(t, http.MethodOptions, http.MethodGet, func(api *API, path string, h http.HandlerFunc) { api.OPTIONS(path, h) })
+}
+
+func TestHandleRouteMiddleware(t *testing.T) {
+	api := newTestAPI()
+	order := ""
+	mark := func(s string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order += s
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	api.GET("/thing", func(w http.ResponseWriter, r *http.Request) { order += "h" }, mark("a"), mark("b"))
+
+	api.handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/thing", nil))
+	if order != "abh" {
+		t.Errorf("ran %q, want %q", order, "abh")
+	}
+}
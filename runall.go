@@ -0,0 +1,84 @@
+package governor
+
+import (
+	"fmt"
+)
+
+// CreateAll initializes the datastore of every app with a dbdriver and
+// creates an API for every app in the configuration, in name order.  Each
+// app listens on its own host and port, so they must not collide.  It stops
+// at the first app which fails, closing the datastores it initialized but
+// leaving open those initialized before it was called; the APIs created so
+// far hold nothing until served and are dropped.
+func (ms *ManagerService) CreateAll() ([]*API, error) {
+	held := ms.openDatastores()
+	if err := ms.InitAllDatastores(true); err != nil {
+		ms.closeDatastoresExcept(held)
+		return nil, fmt.Errorf("CreateAll: %s", err)
+	}
+
+	apis := []*API{}
+	for _, app := range ms.Apps() {
+		api, err := ms.createAPI(app)
+		if err != nil {
+			ms.closeDatastoresExcept(held)
+			return nil, fmt.Errorf("CreateAll: [%s] %s", app, err)
+		}
+		apis = append(apis, api)
+	}
+	return apis, nil
+}
+
+// openDatastores returns the apps whose datastore, gorm connection or
+// backend store, is open.
+func (ms *ManagerService) openDatastores() map[string]bool {
+	ms.dbmu.RLock()
+	defer ms.dbmu.RUnlock()
+
+	open := make(map[string]bool)
+	for app, dbc := range ms.DBConfig {
+		if dbc != nil && dbc.Connection != nil {
+			open[app] = true
+		}
+	}
+	for app := range ms.stores {
+		open[app] = true
+	}
+	return open
+}
+
+// closeDatastoresExcept closes the datastore of every app with a dbdriver
+// which is not in held, undoing InitAllDatastores, and logs any failure.
+func (ms *ManagerService) closeDatastoresExcept(held map[string]bool) {
+	for _, app := range ms.Apps() {
+		if held[app] {
+			continue
+		}
+		if _, err := ms.GetAppProperty(app, "dbdriver"); err != nil {
+			continue
+		}
+		if err := ms.CloseDatastore(app); err != nil {
+			ms.AppLog(app).Warnf("CreateAll: %s", err)
+		}
+	}
+}
+
+// RunAll creates every app with CreateAll and daemonizes them together,
+// blocking until SIGINT or SIGTERM or until any app's server fails, which
+// shuts the others down too.  It returns the first failure, naming its app.
+func (ms *ManagerService) RunAll() error {
+	apis, err := ms.CreateAll()
+	if err != nil {
+		return err
+	}
+	if len(apis) == 0 {
+		return fmt.Errorf("RunAll: configuration defines no apps.")
+	}
+	return ms.DaemonizeAll(apis...)
+}
+
+// DaemonizeAll serves apis together as Daemonize serves one, returning the
+// first server failure, which shuts the others down, or nil after a signal.
+func (ms *ManagerService) DaemonizeAll(apis ...*API) error {
+	return ms.daemonize(apis...)
+}
@@ -0,0 +1,68 @@
+package governor
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateAllClosesOnFailure(t *testing.T) {
+	cases := []struct {
+		name string
+		bad  string
+	}{
+		{"bad api", "[b]\nport = 65536\n"},
+		{"bad datastore", "[b]\ndbdriver = \"nope\"\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "a.db")
+			ms := newTestManager(t, fmt.Sprintf("[a]\ndbdriver = \"sqlite3\"\ndbpath = %q\nport = 0\n\n%s", path, c.bad))
+			t.Cleanup(func() { ms.Close() })
+
+			apis, err := ms.CreateAll()
+			if err == nil {
+				t.Fatalf("created %d APIs, want an error", len(apis))
+			}
+			if _, err := ms.DB("a"); err == nil {
+				t.Error("[a] datastore left open after CreateAll failed")
+			}
+		})
+	}
+}
+
+func TestCreateAllKeepsHeldDatastores(t *testing.T) {
+	dir := t.TempDir()
+	ms := newTestManager(t, fmt.Sprintf(`[a]
+dbdriver = "sqlite3"
+dbpath = %q
+port = 0
+
+[b]
+dbdriver = "sqlite3"
+dbpath = %q
+port = 0
+
+[c]
+port = 65536
+`, filepath.Join(dir, "a.db"), filepath.Join(dir, "b.db")))
+	t.Cleanup(func() { ms.Close() })
+
+	if err := ms.InitDatastore("a"); err != nil {
+		t.Fatalf("InitDatastore: %s", err)
+	}
+	if _, err := ms.CreateAll(); err == nil {
+		t.Fatal("CreateAll succeeded, want the [c] port error")
+	}
+
+	db, err := ms.DB("a")
+	if err != nil {
+		t.Fatalf("[a] datastore initialized before CreateAll was closed: %s", err)
+	}
+	if err := db.DB().Ping(); err != nil {
+		t.Errorf("[a] connection: %s", err)
+	}
+	if _, err := ms.DB("b"); err == nil {
+		t.Error("[b] datastore left open after CreateAll failed")
+	}
+}
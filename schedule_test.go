@@ -0,0 +1,188 @@
+package governor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"", "0", "* * * *", "* * * * * *", "-1m", "@every 0s", "@never",
+		"60 * * * *", "* 24 * * *", "* * 0 * *", "* * 32 * *", "* * * 0 *", "* * * 13 *", "* * * * 8",
+		"5-1 * * * *", "*/0 * * * *", "*/x * * * *", "a * * * *", "1-x * * * *", "1,,2 * * * *",
+	} {
+		if s, err := parseSchedule(spec); err == nil {
+			t.Errorf("%q: got %v, want an error", spec, s)
+		}
+	}
+}
+
+func TestParseCronField(t *testing.T) {
+	cases := []struct {
+		field    string
+		min, max int
+		want     []int
+	}{
+		{"*", 0, 5, []int{0, 1, 2, 3, 4, 5}},
+		{"3", 0, 59, []int{3}},
+		{"0,59", 0, 59, []int{0, 59}},
+		{"1-3", 1, 12, []int{1, 2, 3}},
+		{"*/15", 0, 59, []int{0, 15, 30, 45}},
+		{"10-20/5", 0, 59, []int{10, 15, 20}},
+		{"50/3", 0, 59, []int{50, 53, 56, 59}},
+		{"1-2,5,*/10", 0, 23, []int{0, 1, 2, 5, 10, 20}},
+	}
+	for _, c := range cases {
+		bits, err := parseCronField(c.field, c.min, c.max)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", c.field, err)
+			continue
+		}
+		var want uint64
+		for _, v := range c.want {
+			want |= 1 << uint(v)
+		}
+		if bits != want {
+			t.Errorf("%q: got %b, want %b", c.field, bits, want)
+		}
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	// Wednesday 14 October 2026, 10:07
+	from := time.Date(2026, 10, 14, 10, 7, 30, 0, time.UTC)
+	cases := []struct {
+		spec string
+		want []time.Time
+	}{
+		{"* * * * *", []time.Time{
+			time.Date(2026, 10, 14, 10, 8, 0, 0, time.UTC),
+			time.Date(2026, 10, 14, 10, 9, 0, 0, time.UTC),
+		}},
+		{"*/20 * * * *", []time.Time{
+			time.Date(2026, 10, 14, 10, 20, 0, 0, time.UTC),
+			time.Date(2026, 10, 14, 10, 40, 0, 0, time.UTC),
+			time.Date(2026, 10, 14, 11, 0, 0, 0, time.UTC),
+		}},
+		{"0 9,17 * * *", []time.Time{
+			time.Date(2026, 10, 14, 17, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC),
+		}},
+		{"30 2 * * 1-5", []time.Time{
+			time.Date(2026, 10, 15, 2, 30, 0, 0, time.UTC),
+			time.Date(2026, 10, 16, 2, 30, 0, 0, time.UTC),
+			time.Date(2026, 10, 19, 2, 30, 0, 0, time.UTC),
+		}},
+		{"0 0 * * 0", []time.Time{time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)}},
+		{"0 0 * * 7", []time.Time{time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)}},
+		{"0 0 * * 5-7", []time.Time{
+			time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC),
+		}},
+		// with both day fields restricted a day matching either runs
+		{"0 0 1 * 1", []time.Time{
+			time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC),
+		}},
+		// with one of them unrestricted both must match
+		{"0 0 13 * *", []time.Time{time.Date(2026, 11, 13, 0, 0, 0, 0, time.UTC)}},
+		{"0 0 31 * *", []time.Time{
+			time.Date(2026, 10, 31, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+		}},
+		{"0 0 29 2 *", []time.Time{time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)}},
+		{"@yearly", []time.Time{time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		{"@annually", []time.Time{time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		{"@monthly", []time.Time{time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)}},
+		{"@weekly", []time.Time{time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)}},
+		{"@daily", []time.Time{time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)}},
+		{"@midnight", []time.Time{time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)}},
+		{"@hourly", []time.Time{time.Date(2026, 10, 14, 11, 0, 0, 0, time.UTC)}},
+		{"@every 90m", []time.Time{
+			time.Date(2026, 10, 14, 11, 37, 30, 0, time.UTC),
+			time.Date(2026, 10, 14, 13, 7, 30, 0, time.UTC),
+		}},
+		{"45s", []time.Time{time.Date(2026, 10, 14, 10, 8, 15, 0, time.UTC)}},
+	}
+	for _, c := range cases {
+		s, err := parseSchedule(c.spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", c.spec, err)
+			continue
+		}
+		at := from
+		for i, want := range c.want {
+			at = s.next(at)
+			if !at.Equal(want) {
+				t.Errorf("%q: run %d at %s, want %s", c.spec, i+1, at, want)
+				break
+			}
+		}
+	}
+}
+
+func TestCronScheduleImpossible(t *testing.T) {
+	for _, spec := range []string{"0 0 30 2 *", "0 0 31 4 *"} {
+		s, err := parseSchedule(spec)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %s", spec, err)
+		}
+		if next := s.next(time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)); !next.IsZero() {
+			t.Errorf("%q: next run %s, want none", spec, next)
+		}
+	}
+}
+
+func TestCronScheduleDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %s", err)
+	}
+	cases := []struct {
+		name string
+		spec string
+		from time.Time
+		want []time.Time
+	}{
+		// 2:00 to 3:00 on 8 March 2026 does not exist
+		{name: "skipped hour", spec: "30 2 * * *", from: time.Date(2026, 3, 7, 12, 0, 0, 0, loc), want: []time.Time{
+			time.Date(2026, 3, 9, 2, 30, 0, 0, loc),
+		}},
+		{name: "hourly across the skipped hour", spec: "0 * * * *", from: time.Date(2026, 3, 8, 0, 30, 0, 0, loc), want: []time.Time{
+			time.Date(2026, 3, 8, 1, 0, 0, 0, loc),
+			time.Date(2026, 3, 8, 3, 0, 0, 0, loc),
+		}},
+		{name: "after the skipped hour", spec: "0 3 * * *", from: time.Date(2026, 3, 8, 0, 0, 0, 0, loc), want: []time.Time{
+			time.Date(2026, 3, 8, 3, 0, 0, 0, loc),
+			time.Date(2026, 3, 9, 3, 0, 0, 0, loc),
+		}},
+		// 1:00 to 2:00 on 1 November 2026 happens twice
+		{name: "repeated hour", spec: "30 1 * * *", from: time.Date(2026, 11, 1, 0, 0, 0, 0, loc), want: []time.Time{
+			time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC),
+			time.Date(2026, 11, 2, 6, 30, 0, 0, time.UTC),
+		}},
+		{name: "hourly across the repeated hour", spec: "0 * * * *", from: time.Date(2026, 11, 1, 0, 30, 0, 0, loc), want: []time.Time{
+			time.Date(2026, 11, 1, 5, 0, 0, 0, time.UTC),
+			time.Date(2026, 11, 1, 7, 0, 0, 0, time.UTC),
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := parseSchedule(c.spec)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			at := c.from
+			for i, want := range c.want {
+				at = s.next(at)
+				if !at.Equal(want) {
+					t.Fatalf("run %d at %s, want %s", i+1, at, want.In(loc))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package governor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PropertySchema describes a property an app section is expected to define.
+// Type is one of "string", "int", "bool", "duration", "size" or "strings";
+// an empty Type accepts any value.
+type PropertySchema struct {
+	Name     string
+	Type     string
+	Required bool
+}
+
+// Schema maps app names to the properties expected under their headings.
+type Schema map[string][]PropertySchema
+
+// ValidationProblem is a single problem found by ValidateSchema.
+type ValidationProblem struct {
+	App      string `json:"app"`
+	Property string `json:"property"`
+	Problem  string `json:"problem"`
+}
+
+// ValidationError reports every problem found by ValidateSchema.  Problems
+// can be marshalled to JSON for deploy tooling, while Error gives the same
+// report in human readable form.
+type ValidationError struct {
+	Problems []ValidationProblem `json:"problems"`
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		lines = append(lines, fmt.Sprintf("[%s] %s: %s", p.App, p.Property, p.Problem))
+	}
+	return fmt.Sprintf("Configuration has %d problem(s): %s", len(e.Problems), strings.Join(lines, "; "))
+}
+
+// ValidateSchema checks the configuration against schema, returning a
+// *ValidationError listing every missing required property, every property
+// of the wrong type and every value of the schema's apps left holding an
+// unresolved ${...} placeholder, or nil when the configuration is valid.
+func (ms *ManagerService) ValidateSchema(schema Schema) error {
+	report := &ValidationError{}
+
+	for app, properties := range schema {
+		ms.placeholderProblems(app, report)
+		for _, p := range properties {
+			if _, ok := ms.lookupAppProperty(app, p.Name); !ok {
+				if p.Required {
+					report.add(app, p.Name, "required property is missing")
+				}
+				continue
+			}
+			if err := ms.checkType(app, p); err != nil {
+				report.add(app, p.Name, err.Error())
+			}
+		}
+	}
+
+	if len(report.Problems) > 0 {
+		report.sort()
+		return report
+	}
+	return nil
+}
+
+func (e *ValidationError) add(app string, property string, problem string) {
+	e.Problems = append(e.Problems, ValidationProblem{App: app, Property: property, Problem: problem})
+}
+
+// sort orders problems by app and property so reports are stable.
+func (e *ValidationError) sort() {
+	sort.Slice(e.Problems, func(i, j int) bool {
+		a, b := e.Problems[i], e.Problems[j]
+		if a.App != b.App {
+			return a.App < b.App
+		}
+		return a.Property < b.Property
+	})
+}
+
+// checkType reads p with the typed getter matching its Type.
+func (ms *ManagerService) checkType(app string, p PropertySchema) error {
+	var err error
+	switch p.Type {
+	case "":
+		return nil
+	case "string":
+		_, err = ms.GetAppProperty(app, p.Name)
+	case "int":
+		_, err = ms.GetAppPropertyInt(app, p.Name)
+	case "bool":
+		_, err = ms.GetAppPropertyBool(app, p.Name)
+	case "duration":
+		_, err = ms.GetAppPropertyDuration(app, p.Name)
+	case "size":
+		_, err = ms.GetAppPropertySize(app, p.Name)
+	case "strings":
+		_, err = ms.GetAppPropertyStrings(app, p.Name)
+	default:
+		return fmt.Errorf("schema has unknown type '%s'", p.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("expected %s", p.Type)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package governor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// defaultSecretCmdTimeout bounds a _cmd secret command unless the app sets
+// secret_cmd_timeout.
+const defaultSecretCmdTimeout = 10 * time.Second
+
+// secretCmdWaitDelay is how long a timed out command's output may stay open
+// after it is killed, e.g. held by a child it started, before it is closed.
+const secretCmdWaitDelay = time.Second
+
+// runSecretCommand runs command, a program and its arguments separated by
+// whitespace, and returns its trimmed stdout.  The command is not run through
+// a shell.  It fails on a non-zero exit or when it outlives the app's
+// secret_cmd_timeout.
+func (ms *ManagerService) runSecretCommand(app string, command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", fmt.Errorf("empty command")
+	}
+
+	timeout := defaultSecretCmdTimeout
+	if _, ok := ms.lookupAppProperty(app, "secret_cmd_timeout"); ok {
+		d, err := ms.GetAppPropertyDuration(app, "secret_cmd_timeout")
+		if err != nil {
+			return "", err
+		}
+		timeout = d
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = secretCmdWaitDelay
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("'%s' timed out after %s", args[0], timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("'%s': %s: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("'%s': %s", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
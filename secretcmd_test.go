@@ -0,0 +1,51 @@
+package governor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeScript writes an executable shell script to a temporary directory.
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell available")
+	}
+	path := filepath.Join(t.TempDir(), "secret.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunSecretCommand(t *testing.T) {
+	script := writeScript(t, "echo \"  s3cret \"\n")
+	ms := newTestManager(t, "[testapp]\n")
+
+	got, err := ms.runSecretCommand("testapp", script)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != "s3cret" {
+		t.Errorf("got %q, want %q", got, "s3cret")
+	}
+}
+
+func TestRunSecretCommandTimeout(t *testing.T) {
+	// the background sleep keeps stdout open after the script is killed
+	script := writeScript(t, "sleep 30 &\nsleep 30\n")
+	ms := newTestManager(t, "[testapp]\nsecret_cmd_timeout = \"100ms\"\n")
+
+	start := time.Now()
+	_, err := ms.runSecretCommand("testapp", script)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("got error %v, want a timeout", err)
+	}
+	if took := time.Since(start); took > 100*time.Millisecond+secretCmdWaitDelay+2*time.Second {
+		t.Errorf("took %s to time out", took)
+	}
+}
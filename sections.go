@@ -0,0 +1,155 @@
+package governor
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// strictSectionsLine matches strict_sections = true above the first header.
+var strictSectionsLine = regexp.MustCompile(`^\s*strict_sections\s*=\s*true\s*(#.*)?$`)
+
+// tomlKey matches a bare or quoted TOML key.
+const tomlKey = `(?:[A-Za-z0-9_-]+|"(?:[^"\\]|\\.)*"|'[^']*')`
+
+// tableHeader and arrayHeader match [table] and [[array]] header lines,
+// capturing the dotted key; singleKey matches an undotted one.
+var (
+	tableHeader = regexp.MustCompile(`^\s*\[\s*(` + tomlKey + `(?:\s*\.\s*` + tomlKey + `)*)\s*\]\s*(?:#.*)?$`)
+	arrayHeader = regexp.MustCompile(`^\s*\[\[\s*(` + tomlKey + `(?:\s*\.\s*` + tomlKey + `)*)\s*\]\]\s*(?:#.*)?$`)
+	singleKey   = regexp.MustCompile(`^` + tomlKey + `$`)
+)
+
+// dedupeSections finds top-level [app] sections defined more than once in
+// the TOML file data read from path.  With strict_sections = true at the top
+// of the file that is an error.  Otherwise the last definition wins: earlier
+// ones are blanked out, keeping line numbers intact, and a warning names the
+// section.
+func dedupeSections(path string, data []byte, warnf func(string, ...interface{})) ([]byte, error) {
+	lines := strings.Split(string(data), "\n")
+
+	// starts of each top-level section by name, and where every header is;
+	// lines inside multi-line strings and arrays are never headers
+	starts := map[string][]int{}
+	headers := []int{}
+	strict := false
+	multiline, depth := "", 0
+	for i, line := range lines {
+		if multiline != "" || depth > 0 {
+			multiline, depth = scanLine(line, multiline, depth)
+			continue
+		}
+
+		if arrayHeader.MatchString(line) {
+			headers = append(headers, i)
+			continue
+		}
+		m := tableHeader.FindStringSubmatch(line)
+		if m == nil {
+			if len(headers) == 0 && strictSectionsLine.MatchString(line) {
+				strict = true
+			}
+			multiline, depth = scanLine(line, multiline, depth)
+			continue
+		}
+		headers = append(headers, i)
+		if singleKey.MatchString(m[1]) {
+			name := unquoteKey(m[1])
+			starts[name] = append(starts[name], i)
+		}
+	}
+
+	names := make([]string, 0, len(starts))
+	for name := range starts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	duplicated := false
+	for _, name := range names {
+		at := starts[name]
+		if len(at) < 2 {
+			continue
+		}
+		duplicated = true
+		if strict {
+			return nil, fmt.Errorf("Section [%s] is defined more than once in '%s', at lines %d and %d.", name, path, at[0]+1, at[1]+1)
+		}
+		warnf("Section [%s] is defined %d times in '%s'; using the last, at line %d.", name, len(at), path, at[len(at)-1]+1)
+
+		for _, start := range at[:len(at)-1] {
+			end := len(lines)
+			for _, h := range headers {
+				if h > start {
+					end = h
+					break
+				}
+			}
+			for j := start; j < end; j++ {
+				lines[j] = ""
+			}
+		}
+	}
+
+	if !duplicated {
+		return data, nil
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// scanLine returns the multi-line string delimiter still open, if any, and
+// the depth of unclosed brackets and braces after line, given those before
+// it.  Single-line strings and comments are skipped.
+func scanLine(line string, multiline string, depth int) (string, int) {
+	for i := 0; i < len(line); i++ {
+		if multiline != "" {
+			switch {
+			case multiline == `"""` && line[i] == '\\':
+				i++
+			case strings.HasPrefix(line[i:], multiline):
+				multiline = ""
+				i += 2
+			}
+			continue
+		}
+
+		switch c := line[i]; {
+		case c == '#':
+			return multiline, depth
+		case strings.HasPrefix(line[i:], `"""`), strings.HasPrefix(line[i:], `'''`):
+			multiline = line[i : i+3]
+			i += 2
+		case c == '"':
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+		case c == '\'':
+			for i++; i < len(line) && line[i] != '\''; i++ {
+			}
+		case c == '[', c == '{':
+			depth++
+		case c == ']', c == '}':
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+	return multiline, depth
+}
+
+// unquoteKey returns key without its quotes.
+func unquoteKey(key string) string {
+	switch {
+	case strings.HasPrefix(key, `"`):
+		if s, err := strconv.Unquote(key); err == nil {
+			return s
+		}
+	case strings.HasPrefix(key, "'"):
+		return strings.Trim(key, "'")
+	}
+	return key
+}
@@ -0,0 +1,101 @@
+package governor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDedupeSections(t *testing.T) {
+	cases := []struct {
+		name     string
+		config   string
+		warnings []string
+		wantErr  string
+		// removed lists text the deduplicated config must no longer contain
+		removed []string
+	}{
+		{
+			name:     "duplicate",
+			config:   "[a]\nx = 1\n\n[b]\ny = 2\n\n[a]\nx = 3\n",
+			warnings: []string{"[a] is defined 2 times"},
+			removed:  []string{"x = 1"},
+		},
+		{
+			name:     "header with comment",
+			config:   "[a] # first\nx = 1\n[a]   # second\nx = 2\n",
+			warnings: []string{"[a] is defined 2 times"},
+			removed:  []string{"x = 1"},
+		},
+		{
+			name:     "quoted header",
+			config:   "[a]\nx = 1\n[\"a\"]\nx = 2\n",
+			warnings: []string{"[a] is defined 2 times"},
+			removed:  []string{"x = 1"},
+		},
+		{
+			name:   "nested tables",
+			config: "[a]\nx = 1\n[a.b]\ny = 2\n[a.c]\nz = 3\n[[a.d]]\nw = 1\n[[a.d]]\nw = 2\n",
+		},
+		{
+			name:   "multi-line array rows",
+			config: "[a]\nrows = [\n  [\"a\", \"b\"],\n  [\"a\"],\n  [\"a\"]\n]\nx = 1\n",
+		},
+		{
+			name:   "multi-line array of inline tables",
+			config: "[a]\nrows = [\n  { name = \"]\" },\n  [ \"a\" ],\n]\n[b]\n",
+		},
+		{
+			name:   "multi-line strings",
+			config: "[a]\ntext = \"\"\"\n[a]\n\"\"\"\nraw = '''\n[a]\n'''\n",
+		},
+		{
+			name:   "brackets in strings and comments",
+			config: "[a]\nx = \"[\" # [\ny = '['\n[b]\n",
+		},
+		{
+			name:    "strict",
+			config:  "strict_sections = true\n[a]\n[b]\n[a]\n",
+			wantErr: "Section [a] is defined more than once",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var warnings []string
+			warnf := func(format string, v ...interface{}) {
+				warnings = append(warnings, fmt.Sprintf(format, v...))
+			}
+
+			out, err := dedupeSections("config.toml", []byte(c.config), warnf)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("got error %v, want %q", err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if len(warnings) != len(c.warnings) {
+				t.Fatalf("warnings = %q, want %q", warnings, c.warnings)
+			}
+			for i, w := range c.warnings {
+				if !strings.Contains(warnings[i], w) {
+					t.Errorf("warning %q, want one containing %q", warnings[i], w)
+				}
+			}
+			if len(c.removed) == 0 && string(out) != c.config {
+				t.Errorf("config changed:\n%s", out)
+			}
+			for _, r := range c.removed {
+				if strings.Contains(string(out), r) {
+					t.Errorf("%q not removed:\n%s", r, out)
+				}
+			}
+			if lines := strings.Count(string(out), "\n"); lines != strings.Count(c.config, "\n") {
+				t.Errorf("line count changed from %d to %d", strings.Count(c.config, "\n"), lines)
+			}
+		})
+	}
+}
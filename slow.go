@@ -0,0 +1,78 @@
+package governor
+
+import (
+	"net/http"
+	"time"
+)
+
+// SlowRequestMiddleware is the name of the middleware installed by
+// slow_request_threshold, for use with Exempt.
+const SlowRequestMiddleware = "slow_requests"
+
+// configureSlowRequests installs the slow request middleware when
+// slow_request_threshold is configured for app.
+func (ms *ManagerService) configureSlowRequests(api *API, app string) error {
+	if _, ok := ms.lookupAppProperty(app, "slow_request_threshold"); !ok {
+		return nil
+	}
+
+	threshold, err := ms.GetAppPropertyDuration(app, "slow_request_threshold")
+	if err != nil {
+		return err
+	}
+	api.Use(SlowRequestMiddleware, api.SlowRequests(threshold))
+
+	return nil
+}
+
+// SlowRequests returns middleware which logs, at warn level, the method,
+// path, status and duration of requests taking longer than threshold.
+// Faster requests are not logged.
+func (api *API) SlowRequests(threshold time.Duration) Middleware {
+	ms := api.ManagerService
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := ms.now()
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+
+			if elapsed := ms.now().Sub(start); elapsed > threshold {
+				ms.AppLog(api.app).Warnf("Slow request: %s %s %d %s", r.Method, r.URL.Path, sw.Status(), elapsed)
+			}
+		})
+	}
+}
+
+// statusWriter records the status code a handler responds with.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(b)
+}
+
+// Status returns the response status, 200 if the handler wrote nothing.
+func (sw *statusWriter) Status() int {
+	if sw.status == 0 {
+		return http.StatusOK
+	}
+	return sw.status
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController, so
+// streaming handlers can still flush.
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -0,0 +1,162 @@
+package governor
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// dsnField holds a value for a datastore field from each source.  Optional
+// fields default to empty.
+type dsnField struct {
+	key                  string
+	toml, url, file, env string
+	optional             bool
+}
+
+var dsnFields = []dsnField{
+	{key: "dbserver", toml: "toml-host", url: "url-host", file: "file-host", env: "env-host"},
+	{key: "dbport", toml: "5432", url: "6543", file: "7654", env: "8765"},
+	{key: "database", toml: "toml_db", url: "url_db", file: "file_db", env: "env_db"},
+	{key: "dbuser", toml: "toml_user", url: "url_user", file: "file_user", env: "env_user"},
+	{key: "dbpassword", toml: "toml_pw", url: "url_pw", file: "file_pw", env: "env_pw", optional: true},
+}
+
+// dsnBase are the values of the fields a case does not set.
+var dsnBase = map[string]string{
+	"dbserver":   "db",
+	"dbport":     "5432",
+	"database":   "app",
+	"dbuser":     "user",
+	"dbpassword": "secret",
+}
+
+// dsnURL builds a postgres dburl from values.
+func dsnURL(values map[string]string) string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(values["dbuser"], values["dbpassword"]),
+		Host:   values["dbserver"] + ":" + values["dbport"],
+		Path:   "/" + values["database"],
+	}
+	return u.String()
+}
+
+func TestResolveDatastorePrecedence(t *testing.T) {
+	type sources struct {
+		toml, url, file, env bool
+	}
+	cases := []struct {
+		name    string
+		sources sources
+		// want picks the expected value from the field; without it the
+		// field takes its default, or is missing if required
+		want    func(f dsnField) string
+		wantErr string
+	}{
+		{name: "toml", sources: sources{toml: true}, want: func(f dsnField) string { return f.toml }},
+		{name: "dburl", sources: sources{url: true}, want: func(f dsnField) string { return f.url }},
+		{name: "file over dburl", sources: sources{url: true, file: true}, want: func(f dsnField) string { return f.file }},
+		{name: "env over toml", sources: sources{toml: true, env: true}, want: func(f dsnField) string { return f.env }},
+		{name: "env over file", sources: sources{file: true, env: true}, want: func(f dsnField) string { return f.env }},
+		{name: "env over dburl", sources: sources{url: true, env: true}, want: func(f dsnField) string { return f.env }},
+		{name: "default"},
+		{name: "toml and file", sources: sources{toml: true, file: true}, wantErr: "both set"},
+		{name: "toml disagrees with dburl", sources: sources{toml: true, url: true}, wantErr: "conflicts with dburl"},
+	}
+
+	for _, f := range dsnFields {
+		for _, c := range cases {
+			t.Run(f.key+"/"+c.name, func(t *testing.T) {
+				dir := t.TempDir()
+				config := []string{"[testapp]", `dbdriver = "postgres"`}
+
+				// the other fields come from TOML, or dburl when it is in use
+				urlValues := map[string]string{}
+				for k, v := range dsnBase {
+					if k == f.key {
+						continue
+					}
+					urlValues[k] = v
+					if !c.sources.url {
+						config = append(config, fmt.Sprintf("%s = %q", k, v))
+					}
+				}
+
+				if c.sources.toml {
+					config = append(config, fmt.Sprintf("%s = %q", f.key, f.toml))
+				}
+				if c.sources.url {
+					urlValues[f.key] = f.url
+					config = append(config, fmt.Sprintf("dburl = %q", dsnURL(urlValues)))
+				}
+				if c.sources.file {
+					path := filepath.Join(dir, f.key)
+					if err := os.WriteFile(path, []byte(f.file+"\n"), 0600); err != nil {
+						t.Fatal(err)
+					}
+					config = append(config, fmt.Sprintf("%s_file = %q", f.key, path))
+				}
+				if c.sources.env {
+					t.Setenv(envKey("testapp", f.key), f.env)
+				}
+
+				wantErr := c.wantErr
+				want := func(f dsnField) string { return "" }
+				if c.want != nil {
+					want = c.want
+				} else if wantErr == "" && !f.optional {
+					wantErr = "missing " + f.key
+				}
+
+				ms := newTestManager(t, strings.Join(config, "\n")+"\n")
+				dbc, _, err := ms.resolveDatastore("testapp")
+				if wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), wantErr) {
+						t.Fatalf("got error %v, want one containing %q", err, wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+
+				got := map[string]string{
+					"dbserver":   dbc.Server,
+					"dbport":     dbc.Port,
+					"database":   dbc.Database,
+					"dbuser":     dbc.User,
+					"dbpassword": dbc.Password,
+				}
+				if w := want(f); got[f.key] != w {
+					t.Errorf("%s = %q, want %q", f.key, got[f.key], w)
+				}
+				for k, v := range urlValues {
+					if k != f.key && got[k] != v {
+						t.Errorf("%s = %q, want %q", k, got[k], v)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestResolveDatastoreIntegerPort(t *testing.T) {
+	ms := newTestManager(t, `[testapp]
+dbdriver = "postgres"
+dbserver = "db"
+dbport   = 5432
+database = "app"
+dbuser   = "user"
+`)
+	dbc, _, err := ms.resolveDatastore("testapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if dbc.Port != "5432" {
+		t.Errorf("dbport = %q, want %q", dbc.Port, "5432")
+	}
+}
@@ -0,0 +1,77 @@
+package governor
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// EventWriter sends Server-Sent Events to a connected client.
+type EventWriter interface {
+	// Send writes an event frame and flushes it to the client.  An empty
+	// event sends a message of the default type.  It returns an error once
+	// the client has disconnected.
+	Send(event string, data string) error
+}
+
+// SSE registers handler to stream Server-Sent Events to GET requests for
+// path.  The response is sent with the text/event-stream headers and each
+// Send is flushed immediately; the handler should return once
+// r.Context() is done, which happens when the client disconnects.  The
+// route, including any path variables, is passed through by the
+// request_timeout middleware, which would buffer the stream.
+func (api *API) SSE(path string, handler func(w EventWriter, r *http.Request), mw ...Middleware) {
+	route := api.handle(http.MethodGet, path, func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("Connection", "keep-alive")
+		// ask proxies such as nginx not to buffer the stream
+		h.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		if err := rc.Flush(); err != nil {
+			api.ManagerService.AppLog(api.app).Errorf("SSE: %s does not support streaming: %s", path, err)
+			return
+		}
+		handler(&sseWriter{w: w, rc: rc, r: r}, r)
+	}, mw...)
+
+	api.routesMu.Lock()
+	if api.streams == nil {
+		api.streams = make(map[*mux.Route]bool)
+	}
+	api.streams[route] = true
+	api.routesMu.Unlock()
+}
+
+// sseWriter implements EventWriter over a flushable response.
+type sseWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+	r  *http.Request
+}
+
+func (s *sseWriter) Send(event string, data string) error {
+	if err := s.r.Context().Err(); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+
+	if _, err := s.w.Write([]byte(b.String())); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
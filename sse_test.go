@@ -0,0 +1,42 @@
+package governor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestSSEPathVariableSkipsTimeout(t *testing.T) {
+	ms := newTestManager(t, "[testapp]\nrequest_timeout = \"5s\"\n")
+	api := newTestAPI()
+	if err := ms.configureRequestTimeout(api, "testapp"); err != nil {
+		t.Fatalf("configureRequestTimeout: %s", err)
+	}
+	api.SSE("/events/{id}", func(w EventWriter, r *http.Request) {
+		if err := w.Send("update", "id "+mux.Vars(r)["id"]); err != nil {
+			t.Errorf("Send: %s", err)
+		}
+	})
+	api.GET("/plain/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); ok {
+			t.Error("request_timeout did not wrap a route SSE did not register")
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	api.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events/42", nil))
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "event: update\ndata: id 42\n\n") {
+		t.Errorf("body = %q, want the update event", body)
+	}
+	if !rec.Flushed {
+		t.Error("the stream was not flushed")
+	}
+
+	api.handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/plain/42", nil))
+}
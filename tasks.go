@@ -0,0 +1,225 @@
+package governor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"time"
+)
+
+// TaskStatus reports a scheduled task's state.  LastError is empty when the
+// last run succeeded.
+type TaskStatus struct {
+	App          string    `json:"app"`
+	Name         string    `json:"name"`
+	Schedule     string    `json:"schedule"`
+	Running      bool      `json:"running"`
+	Runs         int64     `json:"runs"`
+	LastRun      time.Time `json:"last_run"`
+	LastDuration string    `json:"last_duration,omitempty"`
+	LastError    string    `json:"last_error,omitempty"`
+	NextRun      time.Time `json:"next_run"`
+}
+
+// task is a job registered with ScheduleTask.
+type task struct {
+	app      string
+	name     string
+	spec     string
+	schedule schedule
+	fn       func(ctx context.Context) error
+
+	// guarded by the manager's tasksMu
+	running  bool
+	runs     int64
+	lastRun  time.Time
+	lastTook time.Duration
+	lastErr  error
+	nextRun  time.Time
+}
+
+// ScheduleTask registers fn to run as the task name of app on spec: an
+// interval such as "10m" or "@every 10m", a macro such as "@hourly" or
+// "@daily", or a five field cron expression such as "30 2 * * 1-5", in the
+// manager clock's local time; cron times skipped when daylight saving time
+// begins do not run, and a repeated hour runs once.  A <name>_schedule key
+// under [app] overrides spec.
+//
+// Tasks run while the manager is serving, from Daemonize, Serve or Start,
+// each in its own goroutine with a context cancelled at shutdown, which
+// waits for running tasks to return.  A run which is still going when the
+// next is due is skipped rather than overlapped.  Errors and panics are
+// logged and recorded in the task's status, see Tasks.
+func (ms *ManagerService) ScheduleTask(app string, name string, spec string, fn func(ctx context.Context) error) error {
+	if override, err := ms.GetAppProperty(app, name+"_schedule"); err == nil {
+		spec = override
+	}
+	s, err := parseSchedule(spec)
+	if err != nil {
+		return fmt.Errorf("ScheduleTask: [%s] %s: %s", app, name, err)
+	}
+
+	t := &task{app: app, name: name, spec: spec, schedule: s, fn: fn}
+
+	ms.tasksMu.Lock()
+	for _, existing := range ms.tasks {
+		if existing.app == app && existing.name == name {
+			ms.tasksMu.Unlock()
+			return fmt.Errorf("ScheduleTask: task '%s' for [%s] is already scheduled.", name, app)
+		}
+	}
+	ms.tasks = append(ms.tasks, t)
+	started := ms.tasksStarted
+	ms.tasksMu.Unlock()
+
+	if started {
+		ms.startTask(t)
+	}
+	return nil
+}
+
+// Tasks returns the status of every scheduled task, sorted by app and name.
+func (ms *ManagerService) Tasks() []TaskStatus {
+	ms.tasksMu.Lock()
+	defer ms.tasksMu.Unlock()
+
+	statuses := make([]TaskStatus, 0, len(ms.tasks))
+	for _, t := range ms.tasks {
+		st := TaskStatus{
+			App:      t.app,
+			Name:     t.name,
+			Schedule: t.spec,
+			Running:  t.running,
+			Runs:     t.runs,
+			LastRun:  t.lastRun,
+			NextRun:  t.nextRun,
+		}
+		if !t.lastRun.IsZero() {
+			st.LastDuration = t.lastTook.String()
+		}
+		if t.lastErr != nil {
+			st.LastError = t.lastErr.Error()
+		}
+		statuses = append(statuses, st)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].App != statuses[j].App {
+			return statuses[i].App < statuses[j].App
+		}
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// startTasks starts every scheduled task, once per manager.
+func (ms *ManagerService) startTasks() {
+	ms.tasksMu.Lock()
+	if ms.tasksStarted {
+		ms.tasksMu.Unlock()
+		return
+	}
+	ms.tasksStarted = true
+	tasks := append([]*task(nil), ms.tasks...)
+	ms.tasksMu.Unlock()
+
+	for _, t := range tasks {
+		ms.startTask(t)
+	}
+}
+
+// startTask runs t's schedule until the manager shuts down.
+func (ms *ManagerService) startTask(t *task) {
+	ctx := ms.context()
+	ms.workers.Add(1)
+	go func() {
+		defer ms.workers.Done()
+
+		for {
+			next := t.schedule.next(ms.now())
+			ms.tasksMu.Lock()
+			t.nextRun = next
+			ms.tasksMu.Unlock()
+			if next.IsZero() {
+				ms.AppLog(t.app).Warnf("Task [%s] %s: schedule '%s' has no further runs.", t.app, t.name, t.spec)
+				return
+			}
+
+			timer := time.NewTimer(next.Sub(ms.now()))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			ms.tasksMu.Lock()
+			busy := t.running
+			t.running = true
+			ms.tasksMu.Unlock()
+			if busy {
+				ms.AppLog(t.app).Warnf("Task [%s] %s: previous run still in progress, skipping.", t.app, t.name)
+				continue
+			}
+
+			ms.workers.Add(1)
+			go func() {
+				defer ms.workers.Done()
+				ms.runTask(ctx, t)
+			}()
+		}
+	}()
+}
+
+// runTask runs t once, recording the outcome.
+func (ms *ManagerService) runTask(ctx context.Context, t *task) {
+	start := ms.now()
+	err := func() (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("panic: %v", p)
+				ms.AppLog(t.app).Errorf("Task [%s] %s panicked: %v\n%s", t.app, t.name, p, debug.Stack())
+			}
+		}()
+		return t.fn(ctx)
+	}()
+	took := ms.now().Sub(start)
+
+	if err != nil {
+		ms.AppLog(t.app).Errorf("Task [%s] %s failed after %s: %s", t.app, t.name, took, err)
+	} else {
+		ms.AppLog(t.app).Debugf("Task [%s] %s completed in %s", t.app, t.name, took)
+	}
+
+	ms.tasksMu.Lock()
+	t.running = false
+	t.runs++
+	t.lastRun = start
+	t.lastTook = took
+	t.lastErr = err
+	ms.tasksMu.Unlock()
+}
+
+// tasksHandler writes the status of the app's tasks as JSON.
+func (ms *ManagerService) tasksHandler(app string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		statuses := []TaskStatus{}
+		for _, st := range ms.Tasks() {
+			if st.App == app {
+				statuses = append(statuses, st)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(statuses)
+	}
+}
+
+// configureTasks mounts GET /admin/tasks behind basic auth when it is
+// configured for app.
+func (ms *ManagerService) configureTasks(api *API, app string) {
+	if auth := ms.adminAuth(app); auth != nil {
+		api.GET("/admin/tasks", auth(ms.tasksHandler(app)).ServeHTTP)
+	}
+}
@@ -0,0 +1,56 @@
+package governor
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTaskSkipsOverlappingRuns(t *testing.T) {
+	ms := newTestManager(t, "[testapp]\n")
+	rec := &recordLogger{}
+	ms.SetLogger(rec)
+
+	var runs int32
+	release := make(chan struct{})
+	err := ms.ScheduleTask("testapp", "slow", "10ms", func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ScheduleTask: %s", err)
+	}
+	ms.startTasks()
+	defer func() {
+		ms.context()
+		ms.cancel()
+		ms.workers.Wait()
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for !strings.Contains(rec.String(), "Task [testapp] slow: previous run still in progress, skipping.") {
+		select {
+		case <-deadline:
+			t.Fatalf("no overlapping run was skipped, logged %q", rec.String())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("%d runs started while the first was still going, want 1", n)
+	}
+	if st := ms.Tasks()[0]; !st.Running || st.Runs != 0 {
+		t.Errorf("status = %+v, want running with no completed runs", st)
+	}
+
+	close(release)
+	for ms.Tasks()[0].Runs < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("runs did not resume after the first completed: %+v", ms.Tasks()[0])
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package governor
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RequestTimeoutMiddleware is the name of the middleware installed by
+// request_timeout, for use with Exempt.
+const RequestTimeoutMiddleware = "request_timeout"
+
+// configureRequestTimeout installs the request timeout middleware when
+// request_timeout is configured for app.
+func (ms *ManagerService) configureRequestTimeout(api *API, app string) error {
+	if _, ok := ms.lookupAppProperty(app, "request_timeout"); !ok {
+		return nil
+	}
+
+	timeout, err := ms.GetAppPropertyDuration(app, "request_timeout")
+	if err != nil {
+		return err
+	}
+	api.Use(RequestTimeoutMiddleware, api.RequestTimeout(timeout))
+
+	return nil
+}
+
+// RequestTimeout returns middleware which bounds each request's context by
+// timeout.  A handler still running when it elapses has its response
+// discarded in favour of a 503 Service Unavailable JSON response, so
+// handlers should watch r.Context() and stop work once it is done.  The
+// response is buffered until the handler returns, so routes registered with
+// SSE are passed through untouched; exempt other streaming endpoints.
+func (api *API) RequestTimeout(timeout time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if api.isStream(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{header: make(http.Header)}
+			done := make(chan struct{})
+			panicked := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				for k, v := range tw.header {
+					w.Header()[k] = v
+				}
+				if tw.status == 0 {
+					tw.status = http.StatusOK
+				}
+				w.WriteHeader(tw.status)
+				w.Write(tw.buf.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				api.WebService.JsonStatusResponse(w, "Request timed out.", http.StatusServiceUnavailable)
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers a handler's response until it completes, discarding
+// writes made after the request timed out.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.header }
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.status != 0 {
+		return
+	}
+	tw.status = status
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.buf.Write(b)
+}
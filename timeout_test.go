@@ -0,0 +1,90 @@
+package governor
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestTimeoutCompletes(t *testing.T) {
+	h := newTestAPI().RequestTimeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); !ok {
+			t.Error("request context has no deadline")
+		}
+		w.Header().Set("X-Handler", "yes")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("made"))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusCreated || rec.Body.String() != "made" || rec.Header().Get("X-Handler") != "yes" {
+		t.Errorf("got %d %q %v, want the handler's response", rec.Code, rec.Body.String(), rec.Header())
+	}
+}
+
+func TestRequestTimeoutExpires(t *testing.T) {
+	wrote := make(chan error, 1)
+	h := newTestAPI().RequestTimeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Before", "set")
+		<-r.Context().Done()
+		// give the middleware time to answer before writing late
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("X-After", "set")
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte("late"))
+		wrote <- err
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if err := <-wrote; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("late Write returned %v, want http.ErrHandlerTimeout", err)
+	}
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("got %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if !strings.Contains(rec.Body.String(), "Request timed out.") || strings.Contains(rec.Body.String(), "late") {
+		t.Errorf("body = %q, want only the timeout response", rec.Body.String())
+	}
+	for _, header := range []string{"X-Before", "X-After"} {
+		if rec.Header().Get(header) != "" {
+			t.Errorf("%s sent with the timeout response", header)
+		}
+	}
+}
+
+func TestRequestTimeoutPanic(t *testing.T) {
+	h := newTestAPI().RequestTimeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("recovered %v, want the handler's panic", p)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	t.Error("the handler's panic was swallowed")
+}
+
+func TestRequestTimeoutNoFlush(t *testing.T) {
+	// flushing the buffered response would defeat the timeout, so the
+	// writer offers neither Flush nor Unwrap
+	h := newTestAPI().RequestTimeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); ok {
+			t.Error("the buffered writer implements http.Flusher")
+		}
+		if _, ok := w.(interface{ Unwrap() http.ResponseWriter }); ok {
+			t.Error("the buffered writer can be unwrapped")
+		}
+		if err := http.NewResponseController(w).Flush(); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("Flush returned %v, want http.ErrNotSupported", err)
+		}
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,136 @@
+package governor
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// tlsVersions maps the accepted tls_min_version values to crypto/tls constants.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// SetTLSConfig replaces the TLS configuration used when the API is daemonized.
+// Certificates may be supplied in cfg directly, in which case tls_cert and
+// tls_key are not required.
+func (api *API) SetTLSConfig(cfg *tls.Config) {
+	api.TLSConfig = cfg
+}
+
+// configureTLS reads tls_cert, tls_key, tls_min_version and tls_cipher_suites
+// for app and prepares the API's TLS configuration.  TLS is only enabled when
+// both a certificate and key are configured.
+func (ms *ManagerService) configureTLS(api *API, app string) error {
+	cert, _ := ms.GetAppProperty(app, "tls_cert")
+	key, _ := ms.GetAppProperty(app, "tls_key")
+
+	if cert == "" && key == "" {
+		return nil
+	}
+	if cert == "" || key == "" {
+		return fmt.Errorf("Both tls_cert and tls_key must be set under [%s] heading.", app)
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if v, err := ms.GetAppProperty(app, "tls_min_version"); err == nil {
+		version, ok := tlsVersions[v]
+		if !ok {
+			return fmt.Errorf("Unknown tls_min_version '%s' under [%s] heading.", v, app)
+		}
+		cfg.MinVersion = version
+	}
+
+	if _, ok := ms.lookupAppProperty(app, "tls_cipher_suites"); ok {
+		names, err := ms.GetAppPropertyStrings(app, "tls_cipher_suites")
+		if err != nil {
+			return err
+		}
+		suites, err := parseCipherSuites(names)
+		if err != nil {
+			return fmt.Errorf("Invalid tls_cipher_suites under [%s] heading: %s", app, err)
+		}
+		cfg.CipherSuites = suites
+	}
+
+	api.TLSConfig = cfg
+	api.tlsCert = cert
+	api.tlsKey = key
+
+	return ms.configureRedirect(api, app, "")
+}
+
+// configureRedirect sets the address of the HTTP listener which redirects to
+// HTTPS, on the API's host at http_redirect_port, given as a number or a
+// string, or at fallback when that is not configured.  With neither, no
+// redirect listener is started.
+func (ms *ManagerService) configureRedirect(api *API, app string, fallback string) error {
+	port := fallback
+	if v, ok := ms.lookupAppProperty(app, "http_redirect_port"); ok {
+		port = strings.TrimSpace(fmt.Sprint(v))
+		if err := checkPort(app, "http_redirect_port", port); err != nil {
+			return err
+		}
+	}
+	if port == "" {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(api.Address)
+	if err != nil {
+		return err
+	}
+	api.redirectAddr = net.JoinHostPort(host, port)
+	return nil
+}
+
+// newRedirectServer creates the HTTP server which redirects to HTTPS, or nil
+// when http_redirect_port is not configured.
+func (api *API) newRedirectServer() *http.Server {
+	if api.redirectAddr == "" {
+		return nil
+	}
+
+	_, port, _ := net.SplitHostPort(api.Address)
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+	if api.challenge != nil {
+		h = api.challenge(h)
+	}
+
+	return &http.Server{Addr: api.redirectAddr, Handler: h}
+}
+
+// parseCipherSuites converts a list of cipher suite names, as reported by
+// tls.CipherSuites, into their IDs.  Insecure suites are rejected.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, cs := range tls.CipherSuites() {
+		known[cs.Name] = cs.ID
+	}
+
+	suites := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite '%s'", name)
+		}
+		suites = append(suites, id)
+	}
+
+	return suites, nil
+}
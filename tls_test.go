@@ -0,0 +1,116 @@
+package governor
+
+import (
+	"crypto/tls"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigureRedirectPort(t *testing.T) {
+	cases := []struct {
+		name    string
+		toml    string
+		env     string
+		want    string
+		wantErr string
+	}{
+		{name: "unset"},
+		{name: "integer", toml: "8080", want: "127.0.0.1:8080"},
+		{name: "string", toml: `"8080"`, want: "127.0.0.1:8080"},
+		{name: "env", env: "8081", want: "127.0.0.1:8081"},
+		{name: "negative", toml: "-1", wantErr: "'http_redirect_port' under [testapp] heading"},
+		{name: "too large", toml: "65536", wantErr: "'http_redirect_port' under [testapp] heading"},
+		{name: "not a number", toml: `"http"`, wantErr: "'http_redirect_port' under [testapp] heading"},
+		{name: "bad env", toml: "8080", env: "http", wantErr: "TESTAPP_HTTP_REDIRECT_PORT environment variable"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := "[testapp]\ntls_cert = \"cert.pem\"\ntls_key = \"key.pem\"\n"
+			if c.toml != "" {
+				config += "http_redirect_port = " + c.toml + "\n"
+			}
+			if c.env != "" {
+				t.Setenv("TESTAPP_HTTP_REDIRECT_PORT", c.env)
+			}
+			ms := newTestManager(t, config)
+			api := newTestAPI()
+			api.Address = "127.0.0.1:8443"
+
+			err := ms.configureTLS(api, "testapp")
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("got error %v, want one naming %s", err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if api.redirectAddr != c.want {
+				t.Errorf("redirect address %q, want %q", api.redirectAddr, c.want)
+			}
+		})
+	}
+}
+
+func TestConfigureTLSPolicy(t *testing.T) {
+	cases := []struct {
+		name       string
+		config     string
+		env        string
+		minVersion uint16
+		suites     []uint16
+		wantErr    string
+	}{
+		{name: "defaults", minVersion: tls.VersionTLS12},
+		{name: "min version", config: `tls_min_version = "1.3"`, minVersion: tls.VersionTLS13},
+		{name: "min version from env", env: "1.1", minVersion: tls.VersionTLS11},
+		{name: "unknown min version", config: `tls_min_version = "1.4"`, wantErr: "Unknown tls_min_version '1.4' under [testapp] heading."},
+		{name: "min version not a number", config: `tls_min_version = "TLS1.2"`, wantErr: "Unknown tls_min_version 'TLS1.2' under [testapp] heading."},
+		{
+			name:       "cipher suites",
+			config:     `tls_cipher_suites = ["TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "tls_ecdhe_ecdsa_with_aes_256_gcm_sha384"]`,
+			minVersion: tls.VersionTLS12,
+			suites:     []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384},
+		},
+		{name: "unknown cipher suite", config: `tls_cipher_suites = ["TLS_MADE_UP"]`, wantErr: "Invalid tls_cipher_suites under [testapp] heading: unknown or insecure cipher suite 'TLS_MADE_UP'"},
+		{name: "insecure cipher suite", config: `tls_cipher_suites = ["TLS_RSA_WITH_RC4_128_SHA"]`, wantErr: "unknown or insecure cipher suite 'TLS_RSA_WITH_RC4_128_SHA'"},
+		{name: "cipher suites not strings", config: `tls_cipher_suites = [1]`, wantErr: "'tls_cipher_suites' under [testapp] heading must contain only strings."},
+		{name: "key without cert", config: `tls_cert = ""`, wantErr: "Both tls_cert and tls_key must be set under [testapp] heading."},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := "[testapp]\n" + c.config + "\n"
+			if !strings.Contains(c.config, "tls_cert") {
+				config += "tls_cert = \"cert.pem\"\n"
+			}
+			config += "tls_key = \"key.pem\"\n"
+			if c.env != "" {
+				t.Setenv("TESTAPP_TLS_MIN_VERSION", c.env)
+			}
+			ms := newTestManager(t, config)
+			api := newTestAPI()
+
+			err := ms.configureTLS(api, "testapp")
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("got error %v, want %q", err, c.wantErr)
+				}
+				if api.TLSConfig != nil {
+					t.Error("TLS configured despite the error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("configureTLS: %s", err)
+			}
+			if api.TLSConfig.MinVersion != c.minVersion {
+				t.Errorf("MinVersion = %#x, want %#x", api.TLSConfig.MinVersion, c.minVersion)
+			}
+			if !reflect.DeepEqual(api.TLSConfig.CipherSuites, c.suites) {
+				t.Errorf("CipherSuites = %v, want %v", api.TLSConfig.CipherSuites, c.suites)
+			}
+		})
+	}
+}
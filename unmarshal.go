@@ -0,0 +1,109 @@
+package governor
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// UnmarshalAppConfig decodes the [app] section into out, which must be a
+// pointer to a struct, using its `toml` struct tags.  Keys missing from the
+// section leave their fields at the zero value.  Environment overrides are
+// applied and, like the typed getters, numeric and boolean fields accept
+// string values.
+func (ms *ManagerService) UnmarshalAppConfig(app string, out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("UnmarshalAppConfig: out must be a non-nil pointer to a struct, got %T.", out)
+	}
+
+	section, err := ms.GetAppSection(app)
+	if err != nil {
+		return err
+	}
+
+	t := rv.Elem().Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := fieldKey(field)
+		if key == "" {
+			continue
+		}
+		value, ok := section[key]
+		if !ok {
+			continue
+		}
+		v, err := coerceField(field.Type, value)
+		if err != nil {
+			return fmt.Errorf("Configuration '%s' under [%s] heading does not fit field %s (%s): %s", key, app, field.Name, field.Type, err)
+		}
+		section[key] = v
+	}
+
+	tree, err := toml.TreeFromMap(section)
+	if err != nil {
+		return fmt.Errorf("UnmarshalAppConfig [%s]: %s", app, err)
+	}
+	if err := tree.Unmarshal(out); err != nil {
+		return fmt.Errorf("UnmarshalAppConfig [%s]: %s", app, err)
+	}
+	return nil
+}
+
+// fieldKey returns the configuration key a struct field is decoded from, or
+// "" when the field is unexported or tagged `toml:"-"`.
+func fieldKey(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+	name := strings.Split(field.Tag.Get("toml"), ",")[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// coerceField converts value to the TOML type expected by a field of type t,
+// parsing strings for numeric and boolean fields.  Kinds it does not know
+// about are left for the TOML unmarshaler to check.
+func coerceField(t reflect.Type, value interface{}) (interface{}, error) {
+	s, isString := value.(string)
+	switch t.Kind() {
+	case reflect.String:
+		if !isString {
+			return nil, fmt.Errorf("expected a string, got %T", value)
+		}
+	case reflect.Bool:
+		if isString {
+			return strconv.ParseBool(strings.TrimSpace(s))
+		}
+		if _, ok := value.(bool); !ok {
+			return nil, fmt.Errorf("expected a boolean, got %T", value)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if isString {
+			return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		}
+		if _, ok := value.(int64); !ok {
+			return nil, fmt.Errorf("expected an integer, got %T", value)
+		}
+	case reflect.Float32, reflect.Float64:
+		switch v := value.(type) {
+		case string:
+			return strconv.ParseFloat(strings.TrimSpace(v), 64)
+		case int64:
+			return float64(v), nil
+		case float64:
+		default:
+			return nil, fmt.Errorf("expected a number, got %T", value)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,46 @@
+package governor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// StartWorkers launches the number of goroutines configured by the app's
+// workers property (default 1), each running fn.  The context passed to fn is
+// cancelled when the manager begins shutting down, and shutdown waits for
+// every worker to return.  It returns an error for an invalid workers property, or once
+// the manager has shut down.
+func (ms *ManagerService) StartWorkers(app string, fn func(ctx context.Context)) error {
+	count := 1
+	if _, ok := ms.lookupAppProperty(app, "workers"); ok {
+		n, err := ms.GetAppPropertyInt(app, "workers")
+		if err != nil {
+			return fmt.Errorf("StartWorkers: %s", err)
+		}
+		if n < 1 {
+			return fmt.Errorf("StartWorkers: Configuration 'workers' under [%s] heading must be at least 1.", app)
+		}
+		count = n
+	}
+
+	// adding under lifeMu orders the workers before shutdown's Wait, which
+	// only starts once the context is cancelled
+	ms.lifeMu.Lock()
+	defer ms.lifeMu.Unlock()
+	if ms.ctx == nil {
+		ms.ctx, ms.cancel = context.WithCancel(context.Background())
+	}
+	ctx := ms.ctx
+	if ctx.Err() != nil {
+		return errors.New("StartWorkers: the manager has shut down.")
+	}
+	for i := 0; i < count; i++ {
+		ms.workers.Add(1)
+		go func() {
+			defer ms.workers.Done()
+			fn(ctx)
+		}()
+	}
+	return nil
+}
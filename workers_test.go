@@ -0,0 +1,57 @@
+package governor
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStartWorkers(t *testing.T) {
+	ms := newTestManager(t, "[testapp]\nworkers = 3\n")
+	var running, stopped int32
+	err := ms.StartWorkers("testapp", func(ctx context.Context) {
+		atomic.AddInt32(&running, 1)
+		<-ctx.Done()
+		atomic.AddInt32(&stopped, 1)
+	})
+	if err != nil {
+		t.Fatalf("StartWorkers: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	api, err := ms.createAPI("testapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { served <- ms.Serve(ctx, api) }()
+	<-api.Listening()
+	cancel()
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return")
+	}
+
+	if atomic.LoadInt32(&running) != 3 || atomic.LoadInt32(&stopped) != 3 {
+		t.Errorf("%d workers ran and %d stopped, want 3", running, stopped)
+	}
+
+	err = ms.StartWorkers("testapp", func(ctx context.Context) {
+		t.Error("worker started after shutdown")
+	})
+	if err == nil || !strings.Contains(err.Error(), "shut down") {
+		t.Errorf("got error %v after shutdown", err)
+	}
+}
+
+func TestStartWorkersInvalid(t *testing.T) {
+	for _, config := range []string{"[testapp]\nworkers = 0\n", "[testapp]\nworkers = \"many\"\n"} {
+		err := newTestManager(t, config).StartWorkers("testapp", func(ctx context.Context) {})
+		if err == nil || !strings.Contains(err.Error(), "[testapp]") {
+			t.Errorf("%q: got error %v, want one naming [testapp]", config, err)
+		}
+	}
+}